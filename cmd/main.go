@@ -14,16 +14,80 @@ import (
 const langDefault = "default"
 const saveStateDefault = "no-state"
 
-// TODO(wilcox-liam): Log Mode
-// commandLineOptions parses and returns the options provided.
-func commandLineOptions() (string, string) {
-	lang := flag.String("lang", "en", "Game Language")
-	saveState := flag.String("state", saveStateDefault, "Save State Name")
+// options are the command line options the game is played with.
+type options struct {
+	lang       string
+	saveState  string
+	name       string
+	pronouns   string
+	logMode    bool
+	report     string
+	theme      string
+	width      int
+	transcript string
+	clear      string
+	tui        bool
+}
+
+// commandLineOptions parses the options provided. The language is validated unless it is to be
+// asked for, and is en for a new game when none is given.
+func commandLineOptions() options {
+	var o options
+	flag.StringVar(&o.lang, "lang", "", "Game Language, en for a new game or the saved language for a saved game")
+	flag.StringVar(&o.saveState, "state", saveStateDefault, "Save State Name")
+	flag.StringVar(&o.name, "name", "", "Player Name, asked for a new game if not given")
+	flag.StringVar(&o.pronouns, "pronouns", "", "Player Pronouns, asked for a new game if not given")
+	flag.BoolVar(&o.logMode, "log", false, "Log warnings to standard error")
+	flag.StringVar(&o.report, "report", "", "Report missing translations compared with a base language")
+	flag.StringVar(&o.theme, "theme", textgame.ThemeFile, "Theme File styling the output")
+	flag.IntVar(&o.width, "width", 0, "Columns to wrap text to, 0 for the width of the terminal")
+	flag.StringVar(&o.transcript, "transcript", "", "File to keep a transcript of the game in")
+	flag.StringVar(&o.clear, "clear", textgame.ClearANSI, "Clearing of the screen: ansi, off or scrollback")
+	flag.BoolVar(&o.tui, "tui", false, "Play in a full screen terminal interface")
 	flag.Parse()
-	if *lang != langDefault {
-		validateLanguage(*lang)
+	if o.lang == "" && o.saveState == saveStateDefault {
+		o.lang = "en"
 	}
-	return *lang, *saveState
+	if o.lang != langDefault && o.lang != "" {
+		validateLanguage(o.lang)
+	}
+	return o
+}
+
+// setOutput sets up the game's output as the options choose: logging, the theme, the width,
+// clearing of the screen and the transcript. If any cannot be set up the game exits.
+func setOutput(o options) {
+	if o.logMode {
+		textgame.SetLog(os.Stderr)
+	}
+	if err := textgame.LoadTheme(o.theme); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	textgame.SetWidth(o.width)
+	if err := textgame.SetClear(o.clear); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if o.transcript != "" {
+		f, err := os.Create(o.transcript)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		textgame.SetTranscript(f)
+	}
+}
+
+// report prints a report on the translations of the game compared with a base language.
+func report(base string) {
+	validateLanguage(base)
+	text, err := textgame.Report(base)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(text)
 }
 
 // validateLanguage checks if a provided language is valid. If not the game exits.
@@ -57,24 +121,43 @@ func contains(s []string, e string) bool {
 }
 
 func main() {
-	lang, saveState := commandLineOptions()
-	if lang == langDefault {
-		lang = language()
+	o := commandLineOptions()
+	setOutput(o)
+	if o.report != "" {
+		report(o.report)
+		return
+	}
+	if o.lang == langDefault {
+		o.lang = language()
 	}
 
 	var game *textgame.Game
 	var err error
-	if saveState == saveStateDefault {
-		game, err = textgame.LoadGameState(textgame.ConfDir + lang)
+	if o.saveState == saveStateDefault {
+		game, err = textgame.NewGame(o.lang)
 	} else {
-		game, err = textgame.LoadGameState(textgame.SaveDir + saveState)
+		game, err = textgame.LoadGameState(textgame.SaveDir+o.saveState, o.lang)
 	}
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	game.Play()
+	if o.saveState == saveStateDefault {
+		err = game.CreatePlayer(o.name, o.pronouns)
+	} else {
+		err = game.SetPlayer(o.name, o.pronouns)
+	}
+	if err != nil {
+		fmt.Print(err)
+		os.Exit(1)
+	}
+
+	if o.tui {
+		game.PlayTUI()
+	} else {
+		game.Play()
+	}
 }
 
 // setExits converts all RoomID's provided by the yaml configuration into a pointer to that room.
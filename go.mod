@@ -2,4 +2,4 @@ module github.com/wilcox-liam/text-game
 
 go 1.14
 
-require gopkg.in/yaml.v2 v2.2.8
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,159 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"time"
+)
+
+// event is a list of actions scheduled to happen at a turn, at a time on the game clock, or
+// after the player has spent a number of turns in a room, declared in the yaml configuration.
+// Events with Every set happen again that many turns after they last did, and events with
+// only conditions happen on the first turn they are met.
+type event struct {
+	At     int
+	Time   string
+	Room   int
+	After  int
+	Every  int
+	When   []condition
+	Do     []action
+	Fired  bool
+	NextAt int
+}
+
+// defaultTimeFormat is the layout of the game clock when the yaml configuration has no TimeFormat.
+const defaultTimeFormat = "15:04"
+
+// freeCommands lists the game commands that do not take a turn.
+var freeCommands = []string{"help", "inventory", "refresh", "save", "load", "quit", "time", "language"}
+
+// takesTurn returns if a command advances the turn counter.
+func takesTurn(command string) bool {
+	for _, key := range freeCommands {
+		if command == key {
+			return false
+		}
+	}
+	return true
+}
+
+// timeFormat returns the layout used to read and show times on the game clock.
+func (g *Game) timeFormat() string {
+	if g.TimeFormat != "" {
+		return g.TimeFormat
+	}
+	return defaultTimeFormat
+}
+
+// parseTime reads a time on the game clock, returned as minutes past midnight.
+func (g *Game) parseTime(value string) (int, error) {
+	t, err := time.Parse(g.timeFormat(), value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// elapsed returns the number of minutes the game clock has advanced since the start.
+func (g *Game) elapsed() int {
+	return g.Turn * g.MinutesPerTurn
+}
+
+// clock returns the current time on the game clock, or "" if the game has no clock.
+func (g *Game) clock() string {
+	if g.StartTime == "" {
+		return ""
+	}
+	start, err := g.parseTime(g.StartTime)
+	if err != nil {
+		return ""
+	}
+	minutes := (start + g.elapsed()) % (24 * 60)
+	return time.Date(0, 1, 1, minutes/60, minutes%60, 0, 0, time.UTC).Format(g.timeFormat())
+}
+
+// showTime prints the current turn, and the time if the game has a clock.
+func (g *Game) showTime() {
+	if g.clock() == "" {
+		fmt.Fprint(screen, render("text", g.str("turn")))
+	} else {
+		fmt.Fprint(screen, render("text", g.str("clock")))
+	}
+	fmt.Fprintln(screen)
+}
+
+// tick advances the turn counter and carries out any events that are due.
+func (g *Game) tick() error {
+	g.Turn++
+	g.TurnsInRoom++
+	err := g.moveNPCs()
+	if err != nil {
+		return err
+	}
+	for index := range g.Events {
+		e := &g.Events[index]
+		if e.Fired || !g.due(e) || !g.met(e.When) {
+			continue
+		}
+		if e.Every > 0 {
+			e.NextAt = g.Turn + e.Every
+		} else {
+			e.Fired = true
+		}
+		err := g.do(e.Do, fmt.Sprintf("Event %d", index+1))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// due returns if an event is scheduled to happen on the current turn.
+func (g *Game) due(e *event) bool {
+	if e.NextAt > 0 {
+		return g.Turn >= e.NextAt
+	}
+	if e.At > 0 && g.Turn < e.At {
+		return false
+	}
+	if e.Time != "" {
+		start, _ := g.parseTime(g.StartTime)
+		at, err := g.parseTime(e.Time)
+		if err != nil || g.elapsed() < (at-start+24*60)%(24*60) {
+			return false
+		}
+	}
+	if e.Room != 0 && (g.CurrentRoomID != e.Room || g.TurnsInRoom < e.After) {
+		return false
+	}
+	return true
+}
+
+// checkEvents validates the events in the yaml configuration and returns a description of
+// each problem found.
+func (g *Game) checkEvents() []string {
+	var problems []string
+	if g.StartTime != "" {
+		if _, err := g.parseTime(g.StartTime); err != nil {
+			problems = append(problems, fmt.Sprintf("Start time %s does not match the time format %s", g.StartTime, g.timeFormat()))
+		}
+	}
+	for index, e := range g.Events {
+		if e.At == 0 && e.Time == "" && e.Room == 0 && e.Every == 0 && len(e.When) == 0 {
+			problems = append(problems, fmt.Sprintf("Event %d has no turn, time, room or conditions", index+1))
+		}
+		if e.Time != "" {
+			if g.StartTime == "" {
+				problems = append(problems, fmt.Sprintf("Event %d has a time but the game has no start time", index+1))
+			} else if _, err := g.parseTime(e.Time); err != nil {
+				problems = append(problems, fmt.Sprintf("Event %d time %s does not match the time format %s", index+1, e.Time, g.timeFormat()))
+			}
+		}
+		if e.Room != 0 && g.getRoomByID(e.Room) == nil {
+			problems = append(problems, fmt.Sprintf("Event %d refers to unknown room %d", index+1, e.Room))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,40 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+)
+
+// frontEnd shows a game to the player and reads their commands. Every front end runs the same
+// game loop, and the game's text is written to the screen output for it to show.
+type frontEnd interface {
+	// open prepares the screen before the game starts.
+	open()
+	// show shows the current state of the game before each command.
+	show(g *Game)
+	// command reads the next command typed by the player, returning an error when there are
+	// no more commands to read.
+	command(g *Game) (string, error)
+	// close restores the screen after the game is over.
+	close()
+}
+
+// lineFrontEnd writes the game line by line, as a story, listing what can be seen in a room
+// whenever it changes.
+type lineFrontEnd struct{}
+
+func (lineFrontEnd) open() {}
+
+func (lineFrontEnd) show(g *Game) {
+	g.showRoom(true)
+}
+
+func (lineFrontEnd) command(g *Game) (string, error) {
+	fmt.Fprint(screen, render("prompt", g.str("command")))
+	line, err := readLine()
+	fmt.Fprintln(screen)
+	return line, err
+}
+
+func (lineFrontEnd) close() {}
@@ -7,24 +7,48 @@ package textgame
 import (
 	"fmt"
 	"strings"
+	"unicode"
 )
 
 // Game provides the data structures to play a text-game
 type Game struct {
 	Name            string
 	Description     string
-	Dictionary      map[string]map[string]string
+	Language        string
+	Dictionary      map[string]map[string]string `yaml:"-"`
 	Player          *player
 	Rooms           []room
+	WinItem         string
+	Variables       map[string]interface{}
+	Rules           []rule
+	Over            bool
+	Scripts         map[string]script
+	ScriptFiles     []string
+	Turn            int
+	TurnsInRoom     int
+	StartTime       string
+	TimeFormat      string
+	MinutesPerTurn  int
+	Events          []event
+	Conversation    conversation
 	CurrentRoomID   int
 	CurrentRoom     *room
 	SavedGame       bool
 	DisplayRoomInfo bool
 	DisplayItemInfo bool
+
+	messages      map[string]string
+	base          *catalog
+	grammar       grammar
+	names         *nameIndex
+	shorthand     []rule
+	scriptGlobals *scriptEnv
+	file          string
 }
 
 type player struct {
 	Name      string
+	Pronouns  string
 	Inventory []item
 }
 
@@ -34,12 +58,24 @@ type room struct {
 	Description string
 	Exits       []exit
 	Items       []item
+	NPCs        []npc
 	Entered     bool
 	StoryString string
+
+	Conditions    []condition
+	BlockedString string
+	Effects       []effect
+	Scripts       map[string]script
+
+	Dark         bool
+	DarkString   string
+	Unsafe       bool
+	UnsafeString string
 }
 
 //Should I use inheritance or interfaces for items and exits?
 type exit struct {
+	ID                string
 	Name              string
 	Description       string
 	Locked            bool
@@ -52,9 +88,30 @@ type exit struct {
 	RoomID    int
 	Direction string
 	GoString  string
+	Door      string
+	OneWay    bool
+
+	Aliases      []string
+	Adjectives   []string
+	Hidden       bool
+	RevealedBy   []string
+	RevealString string
+	Closed       bool
+	Closable     bool
+	OpenString   string
+	CloseString  string
+	Relockable   bool
+	LockString   string
+
+	Conditions    []condition
+	BlockedString string
+	Effects       []effect
+	Scripts       map[string]script
+	Verbs         map[string]verbResponse
 }
 
 type item struct {
+	ID                string
 	Name              string
 	Description       string
 	Locked            bool
@@ -74,6 +131,42 @@ type item struct {
 	Useable    bool
 	UseString  string
 	Items      []item
+
+	Aliases      []string
+	Adjectives   []string
+	Hidden       bool
+	RevealedBy   []string
+	RevealString string
+	Surface      bool
+	Capacity     int
+	Accepts      []string
+
+	Closable    bool
+	CloseString string
+	Relockable  bool
+	LockString  string
+
+	LightSource bool
+	Lit         bool
+	OnString    string
+	OffString   string
+
+	States map[string]itemState
+	State  string
+
+	Conditions    []condition
+	BlockedString string
+	Effects       []effect
+	Scripts       map[string]script
+	Verbs         map[string]verbResponse
+}
+
+// verbResponse is how an item or exit responds to a custom verb from the Game Dictionary.
+type verbResponse struct {
+	Text          string
+	Conditions    []condition
+	BlockedString string
+	Effects       []effect
 }
 
 // itemContainer is an interface for Room, Player and Item
@@ -125,6 +218,9 @@ func (g *Game) getRoomByID(id int) *room {
 // setCurrentRoom sets the room the player is currently in.
 func (g *Game) setCurrentRoom(room *room) {
 	g.displayRoomInfo()
+	if room.ID != g.CurrentRoomID {
+		g.TurnsInRoom = 0
+	}
 	g.CurrentRoom = room
 	g.CurrentRoomID = room.ID
 	g.CurrentRoom.Entered = true
@@ -132,16 +228,26 @@ func (g *Game) setCurrentRoom(room *room) {
 
 func (g *Game) displayRoomInfo() {
 	CallClear()
-	fmt.Println()
+	fmt.Fprintln(screen)
 	g.DisplayRoomInfo = true
 	g.DisplayItemInfo = true
 }
 
-// getExitByName returns an exit matching a provided name in a room.
-// Ignores case.
-func (r *room) getExitByName(name string) *exit {
+// getExitByName returns an exit matching a provided name or alias in the current room.
+func (g *Game) getExitByName(name string) *exit {
+	for _, id := range g.resolve(name) {
+		exit := g.CurrentRoom.getExitByID(id)
+		if exit != nil {
+			return exit
+		}
+	}
+	return nil
+}
+
+// getExitByID returns an exit matching a provided id in a room, if it is not hidden.
+func (r *room) getExitByID(id string) *exit {
 	for index, exit := range r.Exits {
-		if strings.ToLower(exit.Name) == strings.ToLower(name) {
+		if exit.ID == id && !exit.Hidden {
 			return &r.Exits[index]
 		}
 	}
@@ -152,80 +258,199 @@ func (r *room) getExitByName(name string) *exit {
 // Ignores case.
 func (r *room) getExitByDirection(direction string) *exit {
 	for index, exit := range r.Exits {
-		if strings.ToLower(exit.Direction) == strings.ToLower(direction) {
+		if !exit.Hidden && strings.ToLower(exit.Direction) == strings.ToLower(direction) {
 			return &r.Exits[index]
 		}
 	}
 	return nil
 }
 
-// getItemByName will return an item given a name if it is visible
-// to the player.
+// reverseExit returns the exit in the destination room leading back through the same door.
+// Exits are paired by their Door ID, see linkExits.
+func (g *Game) reverseExit(e *exit) *exit {
+	room := g.getRoomByID(e.RoomID)
+	if room == nil || e.Door == "" {
+		return nil
+	}
+	for index, exit := range room.Exits {
+		if exit.Door == e.Door {
+			return &room.Exits[index]
+		}
+	}
+	return nil
+}
+
+// exitsTo returns the exits in a room leading to a provided room id.
+func (r *room) exitsTo(id int) []*exit {
+	var exits []*exit
+	for index, exit := range r.Exits {
+		if exit.RoomID == id {
+			exits = append(exits, &r.Exits[index])
+		}
+	}
+	return exits
+}
+
+// getItemByName will return an item given a name or alias if it is visible
+// to the player. Items in the room are preferred over items in the inventory.
+// Items in a dark room cannot be seen.
 func (g *Game) getItemByName(name string) *item {
-	item := g.CurrentRoom.getItemByName(name)
+	var item *item
+	if g.isLit(g.CurrentRoom) {
+		item = g.findItem(name, g.CurrentRoom)
+	}
 	if item == nil {
-		item = g.Player.getItemByName(name)
+		item = g.findItem(name, g.Player)
 	}
 	return item
 }
 
-// getItemByName returns an item in a room if it is visible to the player.
-func (r *room) getItemByName(name string) *item {
-	return getItemByName(name, r)
-}
-
-// getItemByName returns an item in a a player's inventory if it is visible to the player.
-func (p *player) getItemByName(name string) *item {
-	return getItemByName(name, p)
+// findItem returns an item matching a provided name or alias in an itemContainer.
+// Only returns an item if it is visible to the player. i.e not inside an unopened container.
+func (g *Game) findItem(name string, ic itemContainer) *item {
+	for _, id := range g.resolve(name) {
+		item := getItemByID(id, ic)
+		if item != nil {
+			return item
+		}
+	}
+	return nil
 }
 
-// getItemByName returns an item in a item if it is visible to the player.
-func (i *item) getItemByName(name string) *item {
-	return getItemByName(name, i)
+// getItemByID will return an item given an id if it is visible to the player.
+func (g *Game) getItemByID(id string) *item {
+	var item *item
+	if g.isLit(g.CurrentRoom) {
+		item = getItemByID(id, g.CurrentRoom)
+	}
+	if item == nil {
+		item = getItemByID(id, g.Player)
+	}
+	return item
 }
 
-// getItemByName returns an Item matching a provided name in an ItemContainer.
-// Only returns an item if it is visible to the player. i.e not inside an unopened container.
-// Ignores Case.
-func getItemByName(name string, ic itemContainer) *item {
+// getItemByID returns an Item matching a provided id in an ItemContainer.
+// Only returns an item if it is visible to the player. i.e not hidden or inside an unopened container.
+func getItemByID(id string, ic itemContainer) *item {
 	items := ic.getItems()
 	for index, item := range items {
-		if strings.ToLower(item.Name) == strings.ToLower(name) {
+		if item.Hidden {
+			continue
+		}
+		if item.ID == id {
 			return &items[index]
 		}
-		if item.Open {
-			subItem := item.getItemByName(name)
+		if item.contentsVisible() {
+			subItem := getItemByID(id, &items[index])
 			if subItem != nil {
 				return subItem
 			}
 		}
+	}
+	return nil
+}
+
+// itemByID returns the item with an id wherever it is in the game, visible or not.
+func (g *Game) itemByID(id string) *item {
+	var found *item
+	find := func(i *item) {
+		if found == nil && i.ID == id {
+			found = i
+		}
+	}
+	walkItems(g.Player, find)
+	for r := range g.Rooms {
+		walkItems(&g.Rooms[r], find)
+		for n := range g.Rooms[r].NPCs {
+			walkItems(&g.Rooms[r].NPCs[n], find)
+		}
+	}
+	return found
+}
 
+// exitByID returns the exit with an id in any room, hidden or not.
+func (g *Game) exitByID(id string) *exit {
+	for r := range g.Rooms {
+		for e := range g.Rooms[r].Exits {
+			if g.Rooms[r].Exits[e].ID == id {
+				return &g.Rooms[r].Exits[e]
+			}
+		}
 	}
 	return nil
 }
 
-// getItemOptions returns a formatted string of all items within a room.
-func (r *room) getItemOptions() string {
-	return getItemOptions(r)
+// reveal makes the item or exit with an id visible to the player.
+// Returns false if there is no such object.
+func (g *Game) reveal(id string) bool {
+	if item := g.itemByID(id); item != nil {
+		item.Hidden = false
+		g.DisplayItemInfo = true
+		return true
+	}
+	if exit := g.exitByID(id); exit != nil {
+		exit.Hidden = false
+		g.DisplayItemInfo = true
+		return true
+	}
+	return false
+}
+
+// walkItems calls fn for every item in an itemContainer, including items inside other items
+// whether they are visible or not.
+func walkItems(ic itemContainer, fn func(*item)) {
+	items := ic.getItems()
+	for index := range items {
+		fn(&items[index])
+		walkItems(&items[index], fn)
+	}
+}
+
+// walkVisibleItems calls fn for every item in an itemContainer the player could see, leaving
+// out the items inside hidden items or closed containers.
+func walkVisibleItems(ic itemContainer, fn func(*item)) {
+	items := ic.getItems()
+	for index := range items {
+		fn(&items[index])
+		if !items[index].Hidden && items[index].contentsVisible() {
+			walkVisibleItems(&items[index], fn)
+		}
+	}
 }
 
-// GetItemOptions returns a formatted string of all items in a player's inventory.
-func (p *player) getItemOptions() string {
-	return getItemOptions(p)
+// is returns if an item is the object referred to by a reference in the yaml configuration.
+// References are object IDs, written in any case and with spaces in place of dashes.
+func (i *item) is(ref string) bool {
+	return i.ID == toID(ref)
 }
 
-// getItemOptions returns a formatted string of all items within an item.
-func (i *item) getItemOptions() string {
-	return getItemOptions(i)
+// toID converts a name or reference into the form used for object IDs.
+// e.g "Jazminne's Bedside Table" becomes "jazminnes-bedside-table".
+func toID(name string) string {
+	name = strings.Replace(strings.ToLower(name), "'", "", -1)
+	var id []rune
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			id = append(id, r)
+		} else if len(id) > 0 && id[len(id)-1] != '-' {
+			id = append(id, '-')
+		}
+	}
+	return strings.TrimRight(string(id), "-")
 }
 
-// getItemOptions returns a formatted string of all items in an itemContainer.
-func getItemOptions(ic itemContainer) string {
+// getItemOptions returns a formatted string of all items in an itemContainer, such as a room,
+// the player's inventory or another item.
+func (g *Game) getItemOptions(ic itemContainer) string {
 	var options string
-	for _, item := range ic.getItems() {
-		options += " [" + item.Name
-		if item.Open {
-			options += item.getItemOptions()
+	items := ic.getItems()
+	for index, item := range items {
+		if item.Hidden {
+			continue
+		}
+		options += " [" + render("items", g.text(item.Name))
+		if item.contentsVisible() {
+			options += g.getItemOptions(&items[index])
 		}
 		options += "]"
 	}
@@ -233,28 +458,71 @@ func getItemOptions(ic itemContainer) string {
 }
 
 // getExitOptions returns a formatted string of all Exits in a Room name.
-func (r *room) getExitOptions() string {
+func (g *Game) getExitOptions(r *room) string {
 	var exitNames string
 	for _, exit := range r.Exits {
-		exitNames += " [" + exit.Name + "]"
+		if exit.Hidden {
+			continue
+		}
+		exitNames += " [" + render("exits", g.text(exit.Name)) + "]"
 	}
 	return exitNames
 }
 
 // getDirections returns a formatted string of all exits in a room by direction.
-func (r *room) getDirections() string {
+func (g *Game) getDirections(r *room) string {
 	var directions string
 	for _, exit := range r.Exits {
-		directions += "[" + exit.Direction + "] "
+		if exit.Hidden {
+			continue
+		}
+		directions += "[" + render("directions", g.directionName(exit.Direction)) + "] "
 	}
 	return directions
 }
 
+// directionName returns the name of a direction key in the Game Dictionary.
+func (g *Game) directionName(key string) string {
+	if name, ok := g.Dictionary["directions"][key]; ok {
+		return name
+	}
+	return key
+}
+
+// contentsVisible returns if the items inside or on top of an item can be seen by the player.
+func (i *item) contentsVisible() bool {
+	return i.Open || i.Surface
+}
+
+// accepts returns if an item may be placed inside or on top of a container item.
+func (i *item) accepts(other *item) bool {
+	if len(i.Accepts) == 0 {
+		return true
+	}
+	for _, accepted := range i.Accepts {
+		if other.is(accepted) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFull returns if a container item has no space left for another item.
+// A Capacity of 0 means the container has no limit.
+func (i *item) isFull() bool {
+	return i.Capacity > 0 && len(i.Items) >= i.Capacity
+}
+
 /////////////////////
 
-// Pop returns and removes an item from a room
-func (r *room) pop(name string) *item {
-	return pop(name, r)
+// pop returns and removes an item from a room.
+func (r *room) pop(id string) *item {
+	return pop(id, r)
+}
+
+// pop returns and removes an item from a player's inventory.
+func (p *player) pop(id string) *item {
+	return pop(id, p)
 }
 
 // Remove an item from a slice - maintaining order
@@ -262,20 +530,18 @@ func remove(slice []item, s int) []item {
 	return append(slice[:s], slice[s+1:]...)
 }
 
-// Pop returns and removes an item from an ItemContainer
-// Bug(wilcox-liam): Better abstraction, this became specific to the take command
-func pop(name string, ic itemContainer) *item {
+// pop returns and removes a visible item with a matching id from an itemContainer.
+// The item is detached regardless of its flags, callers are responsible for
+// checking if the item may be moved.
+func pop(id string, ic itemContainer) *item {
 	items := ic.getItems()
 	for index, item := range items {
-		if strings.ToLower(item.Name) == strings.ToLower(name) {
-			if item.Takeable {
-				items = remove(items, index)
-				ic.setItems(items)
-			}
+		if item.ID == id {
+			ic.setItems(remove(items, index))
 			return &item
 		}
-		if item.Open {
-			subItem := pop(name, &items[index])
+		if item.contentsVisible() {
+			subItem := pop(id, &items[index])
 			if subItem != nil {
 				return subItem
 			}
@@ -283,3 +549,37 @@ func pop(name string, ic itemContainer) *item {
 	}
 	return nil
 }
+
+// detach returns and removes the item with an id from wherever it is in the game,
+// visible or not.
+func (g *Game) detach(id string) *item {
+	item := detachFrom(id, g.Player)
+	for r := 0; item == nil && r < len(g.Rooms); r++ {
+		item = detachFrom(id, &g.Rooms[r])
+		for n := 0; item == nil && n < len(g.Rooms[r].NPCs); n++ {
+			item = detachFrom(id, &g.Rooms[r].NPCs[n])
+		}
+	}
+	return item
+}
+
+// detachFrom returns and removes the item with an id from an itemContainer or any item inside it.
+func detachFrom(id string, ic itemContainer) *item {
+	items := ic.getItems()
+	for index, item := range items {
+		if item.ID == id {
+			ic.setItems(remove(items, index))
+			return &item
+		}
+		subItem := detachFrom(id, &items[index])
+		if subItem != nil {
+			return subItem
+		}
+	}
+	return nil
+}
+
+// push adds an item to an itemContainer.
+func push(i item, ic itemContainer) {
+	ic.setItems(append(ic.getItems(), i))
+}
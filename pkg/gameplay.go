@@ -7,94 +7,395 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
-	"strings"
 )
 
 // goDirection handles user input command.go and will set CurrentRoom to the new room.
 func (g *Game) goDirection(where string) error {
 	exit := g.CurrentRoom.getExitByDirection(where)
 	if exit == nil {
-		exit = g.CurrentRoom.getExitByName(where)
+		exit = g.getExitByName(where)
 		if exit == nil {
-			return fmt.Errorf(g.Dictionary["errors"]["noExit"], g.CurrentRoom.Name, where)
+			return g.errorf("noExit", vars{"direction": where})
 		}
 	}
 	if exit.Locked {
-		return errors.New(exit.LockedString)
+		return errors.New(g.text(exit.LockedString))
+	}
+	if exit.Closed {
+		return g.errorf("exitClosed", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	if !g.met(exit.Conditions) {
+		return g.blocked(g.text(exit.BlockedString))
 	}
 	nextRoom := g.getRoomByID(exit.RoomID)
+	if !g.met(nextRoom.Conditions) {
+		return g.blocked(g.text(nextRoom.BlockedString))
+	}
+	if nextRoom.Unsafe && !g.isLit(nextRoom) {
+		return errors.New(g.unsafeString(nextRoom))
+	}
 	entered := nextRoom.Entered
+	g.apply(exit.Effects)
+	g.apply(nextRoom.Effects)
 	g.setCurrentRoom(nextRoom)
 	if entered == false && nextRoom.StoryString != "" {
-		fmt.Print(nextRoom.StoryString)
+		fmt.Fprint(screen, render("story", g.text(nextRoom.StoryString)))
 	}
-	fmt.Printf(exit.GoString)
+	fmt.Fprint(screen, render("text", g.text(exit.GoString)))
 	//fmt.Println()
 	return nil
 }
 
-// examine will return the description of an object matching the
+// examine will return the description of an object or character matching the
 // provided name or direction.
 func (g *Game) examine(name string) error {
 	item := g.getItemByName(name)
 	if item != nil {
-		fmt.Println(item.Description)
+		fmt.Fprintln(screen, render("text", g.text(item.Description)))
+		g.revealBy(item.ID)
+		return nil
+	}
+	if n := g.getNPCByName(name); n != nil {
+		fmt.Fprintln(screen, render("text", g.text(n.Description)))
 		return nil
 	}
 	// Exits in the Room
-	exit := g.CurrentRoom.getExitByName(name)
+	exit := g.getExitByName(name)
 	if exit != nil {
-		fmt.Println("(" + exit.Direction + "): " + exit.Description)
+		fmt.Fprintln(screen, "("+render("directions", g.directionName(exit.Direction))+"): "+render("text", g.text(exit.Description)))
+		g.revealBy(exit.ID)
 		return nil
 	}
 	exit = g.CurrentRoom.getExitByDirection(name)
 	if exit != nil {
-		fmt.Println("(" + exit.Name + "): " + exit.Description)
+		fmt.Fprintln(screen, "("+render("exits", g.text(exit.Name))+"): "+render("text", g.text(exit.Description)))
+		g.revealBy(exit.ID)
 		return nil
 	}
-	return fmt.Errorf(g.Dictionary["errors"]["noObject"], name, g.CurrentRoom.Name)
+	return g.errorf("noObject", vars{"name": name})
+}
+
+// turnOn will turn on a light source in the room or inventory.
+func (g *Game) turnOn(name string) error {
+	item := g.getItemByName(name)
+	if item == nil {
+		return g.errorf("noItem", vars{"name": name})
+	}
+	if !item.LightSource {
+		return g.errorf("notLightSource", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if item.Lit {
+		return g.errorf("alreadyOn", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if item.Locked {
+		return g.errorf("noPower", vars{"item": g.named(item.ID, item.Name)})
+	}
+	wasLit := g.isLit(g.CurrentRoom)
+	item.Lit = true
+	fmt.Fprintln(screen, render("text", g.text(item.OnString)))
+	g.refreshLight(wasLit)
+	return nil
+}
+
+// turnOff will turn off a lit light source in the room or inventory.
+func (g *Game) turnOff(name string) error {
+	item := g.getItemByName(name)
+	if item == nil {
+		return g.errorf("noItem", vars{"name": name})
+	}
+	if !item.LightSource {
+		return g.errorf("notLightSource", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if !item.Lit {
+		return g.errorf("alreadyOff", vars{"item": g.named(item.ID, item.Name)})
+	}
+	wasLit := g.isLit(g.CurrentRoom)
+	item.Lit = false
+	fmt.Fprintln(screen, render("text", g.text(item.OffString)))
+	g.refreshLight(wasLit)
+	return nil
+}
+
+// refreshLight displays the room again if turning a light source on or off changed
+// whether the player can see.
+func (g *Game) refreshLight(wasLit bool) {
+	g.DisplayItemInfo = true
+	if g.isLit(g.CurrentRoom) != wasLit {
+		g.DisplayRoomInfo = true
+	}
+}
+
+// search looks for hidden objects in the room, or revealed by a visible item or exit.
+func (g *Game) search(name string) error {
+	id := ""
+	if name != "" && normalise(name) != normalise(g.text(g.CurrentRoom.Name)) {
+		ids := g.visibleIDs(name)
+		if len(ids) == 0 {
+			return g.errorf("noObject", vars{"name": name})
+		}
+		id = ids[0]
+	}
+	if g.revealBy(id) == 0 {
+		fmt.Fprintln(screen, render("text", g.str("nothingFound")))
+	}
+	return nil
 }
 
 // open will set the Open attribute of a visible item to true.
+// Closed exits in the room can be opened too.
 func (g *Game) open(name string) error {
 	item := g.getItemByName(name)
 	if item == nil {
-		return fmt.Errorf(g.Dictionary["errors"]["noItem"], name, g.CurrentRoom.Name)
+		exit := g.getExitByName(name)
+		if exit == nil {
+			return g.errorf("noItem", vars{"name": name})
+		}
+		return g.openExit(exit)
 	}
 	//return if item is already open or cannot be opened.
 	if item.Open {
-		return fmt.Errorf(g.Dictionary["errors"]["itemOpen"], item.Name)
+		return g.errorf("itemOpen", vars{"item": g.named(item.ID, item.Name)})
 	}
 	if item.Openable == false {
-		return fmt.Errorf(g.Dictionary["errors"]["itemNotOpenable"], item.Name)
+		return g.errorf("itemNotOpenable", vars{"item": g.named(item.ID, item.Name)})
 	}
 	if item.Locked == true {
-		return errors.New(item.LockedString)
+		return errors.New(g.text(item.LockedString))
 	}
 	item.Open = true
 	g.DisplayItemInfo = true
-	fmt.Println(item.OpenString)
+	fmt.Fprintln(screen, render("text", g.text(item.OpenString)))
+	return nil
+}
+
+// openExit will open a closed exit, and the matching exit in the other room.
+func (g *Game) openExit(exit *exit) error {
+	if !exit.Closed {
+		return g.errorf("itemOpen", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	if exit.Locked {
+		return errors.New(g.text(exit.LockedString))
+	}
+	exit.Closed = false
+	g.syncExit(exit)
+	fmt.Fprintln(screen, render("text", g.text(exit.OpenString)))
+	return nil
+}
+
+// close will set the Open attribute of a visible item to false.
+// The item must be flagged as closable. Exits in the room can be closed too.
+func (g *Game) close(name string) error {
+	item := g.getItemByName(name)
+	if item == nil {
+		exit := g.getExitByName(name)
+		if exit == nil {
+			return g.errorf("noItem", vars{"name": name})
+		}
+		return g.closeExit(exit)
+	}
+	if !item.Open {
+		return g.errorf("itemClosedAlready", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if !item.Closable {
+		return g.errorf("itemNotClosable", vars{"item": g.named(item.ID, item.Name)})
+	}
+	item.Open = false
+	g.DisplayItemInfo = true
+	fmt.Fprintln(screen, render("text", g.text(item.CloseString)))
+	return nil
+}
+
+// closeExit will close an open exit, and the matching exit in the other room.
+func (g *Game) closeExit(exit *exit) error {
+	if exit.Closed {
+		return g.errorf("itemClosedAlready", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	if !exit.Closable {
+		return g.errorf("itemNotClosable", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	exit.Closed = true
+	g.syncExit(exit)
+	fmt.Fprintln(screen, render("text", g.text(exit.CloseString)))
+	return nil
+}
+
+// lock will lock a visible item or exit using a key item.
+// The object must be flagged as relockable and the key must be the one that unlocks it.
+func (g *Game) lock(name string, with string) error {
+	if with == "" {
+		return g.errorf("lockWith", vars{"name": name})
+	}
+	key := g.getItemByName(with)
+	if key == nil {
+		return g.errorf("noItem", vars{"name": with})
+	}
+	item := g.getItemByName(name)
+	if item == nil {
+		exit := g.getExitByName(name)
+		if exit == nil {
+			return g.errorf("noItem", vars{"name": name})
+		}
+		return g.lockExit(key, exit)
+	}
+	if item.Locked {
+		return g.errorf("itemLocked", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if !item.Relockable {
+		return g.errorf("itemNotLockable", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if !key.is(item.UnlockedWith) {
+		return g.errorf("cannotUseItem", vars{"item": g.named(key.ID, key.Name), "target": g.named(item.ID, item.Name)})
+	}
+	if item.Open {
+		return g.errorf("closeFirst", vars{"item": g.named(item.ID, item.Name)})
+	}
+	item.Locked = true
+	fmt.Fprintln(screen, render("text", g.text(item.LockString)))
+	return nil
+}
+
+// lockExit will lock an exit using a key item, and the matching exit in the other room.
+func (g *Game) lockExit(key *item, exit *exit) error {
+	if exit.Locked {
+		return g.errorf("itemLocked", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	if !exit.Relockable {
+		return g.errorf("itemNotLockable", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	if !key.is(exit.UnlockedWith) {
+		return g.errorf("cannotUseItem", vars{"item": g.named(key.ID, key.Name), "target": g.named(exit.ID, exit.Name)})
+	}
+	if exit.Closable && !exit.Closed {
+		return g.errorf("closeFirst", vars{"item": g.named(exit.ID, exit.Name)})
+	}
+	exit.Locked = true
+	g.syncExit(exit)
+	fmt.Fprintln(screen, render("text", g.text(exit.LockString)))
+	return nil
+}
+
+// unlock will unlock a visible item or exit using a key item.
+func (g *Game) unlock(name string, with string) error {
+	if with == "" {
+		return g.errorf("unlockWith", vars{"name": name})
+	}
+	key := g.getItemByName(with)
+	if key == nil {
+		return g.errorf("noItem", vars{"name": with})
+	}
+	item := g.getItemByName(name)
+	if item == nil {
+		exit := g.getExitByName(name)
+		if exit == nil {
+			return g.errorf("noItem", vars{"name": name})
+		}
+		if !exit.Locked {
+			return g.errorf("itemNotLocked", vars{"item": g.named(exit.ID, exit.Name)})
+		}
+		if !key.is(exit.UnlockedWith) {
+			return g.errorf("cannotUseItem", vars{"item": g.named(key.ID, key.Name), "target": g.named(exit.ID, exit.Name)})
+		}
+		g.unlockExit(exit)
+		return nil
+	}
+	if !item.Locked {
+		return g.errorf("itemNotLocked", vars{"item": g.named(item.ID, item.Name)})
+	}
+	if !key.is(item.UnlockedWith) {
+		return g.errorf("cannotUseItem", vars{"item": g.named(key.ID, key.Name), "target": g.named(item.ID, item.Name)})
+	}
+	g.unlockItem(item)
 	return nil
 }
 
 // take will remove an item from the room and add it to a players inventory.
 // The item must be flagged as takeable.
 func (g *Game) take(name string) error {
-	item := g.CurrentRoom.pop(name)
+	var item *item
+	if g.isLit(g.CurrentRoom) {
+		item = g.findItem(name, g.CurrentRoom)
+	}
 	if item == nil {
-		return fmt.Errorf(g.Dictionary["errors"]["noItem"], name, g.CurrentRoom.Name)
+		return g.errorf("noItem", vars{"name": name})
 	}
-	if item.Takeable {
-		g.DisplayItemInfo = true
-		g.Player.Inventory = append(g.Player.Inventory, *item)
-		fmt.Printf(g.Dictionary["strings"]["itemAdded"], item.Name)
-		fmt.Println()
-		return nil
+	if !item.Takeable {
+		if item.NotTakeableString != "" {
+			return errors.New(g.text(item.NotTakeableString))
+		}
+		return g.errorf("itemNotTakeable")
 	}
-	if item.NotTakeableString != "" {
-		return fmt.Errorf(item.NotTakeableString)
+	item = g.CurrentRoom.pop(item.ID)
+	g.DisplayItemInfo = true
+	g.Player.Inventory = append(g.Player.Inventory, *item)
+	fmt.Fprint(screen, render("text", g.str("itemAdded", vars{"item": g.named(item.ID, item.Name)})))
+	fmt.Fprintln(screen)
+	return nil
+}
+
+// drop will remove an item from a players inventory and leave it in the room.
+func (g *Game) drop(name string) error {
+	item := g.findItem(name, g.Player)
+	if item == nil {
+		return g.errorf("notInInventory", vars{"name": name})
 	}
-	return fmt.Errorf(g.Dictionary["errors"]["itemNotTakeable"])
+	item = g.Player.pop(item.ID)
+	g.DisplayItemInfo = true
+	push(*item, g.CurrentRoom)
+	fmt.Fprint(screen, render("text", g.str("itemDropped", vars{"item": g.named(item.ID, item.Name)})))
+	fmt.Fprintln(screen)
+	return nil
+}
+
+// put will move an item from a players inventory into or on top of another item.
+// The preposition decides if the target must be an open container or a surface.
+func (g *Game) put(name string, preposition string, on string) error {
+	if on == "" {
+		return g.errorf("putWhere", vars{"name": name})
+	}
+	item := g.findItem(name, g.Player)
+	if item == nil {
+		return g.errorf("notInInventory", vars{"name": name})
+	}
+	target := g.getItemByName(on)
+	if target == nil {
+		return g.errorf("noItem", vars{"name": on})
+	}
+	if target == item || getItemByID(target.ID, item) != nil {
+		return g.errorf("putInSelf", vars{"item": g.named(item.ID, item.Name)})
+	}
+	switch preposition {
+	case "in":
+		if !target.Open && target.Openable {
+			return g.errorf("itemClosed", vars{"item": g.named(target.ID, target.Name)})
+		}
+		if !target.Open {
+			return g.errorf("notContainer", vars{"item": g.named(target.ID, target.Name)})
+		}
+	case "on":
+		if !target.Surface {
+			return g.errorf("notSurface", vars{"item": g.named(target.ID, target.Name)})
+		}
+	default:
+		return g.errorf("putWhere", vars{"name": name})
+	}
+	if !target.accepts(item) {
+		return g.errorf("itemNotAccepted", vars{"item": g.named(item.ID, item.Name), "target": g.named(target.ID, target.Name)})
+	}
+	if target.isFull() {
+		return g.errorf("containerFull", vars{"item": g.named(target.ID, target.Name)})
+	}
+	// Popping from the inventory can move the target in memory if it is also carried.
+	targetID, targetName := target.ID, g.named(target.ID, target.Name)
+	moved := g.Player.pop(item.ID)
+	push(*moved, g.getItemByID(targetID))
+	g.DisplayItemInfo = true
+	fmt.Fprint(screen, render("text", g.str("itemPut", vars{
+		"item":        g.named(moved.ID, moved.Name),
+		"preposition": g.Dictionary["prepositions"][preposition],
+		"target":      targetName,
+	})))
+	fmt.Fprintln(screen)
+	return nil
 }
 
 // use actions the use function of an item in a players inventory or the room.
@@ -102,21 +403,25 @@ func (g *Game) take(name string) error {
 func (g *Game) use(name string, on string) error {
 	item := g.getItemByName(name)
 	if item == nil {
-		return fmt.Errorf(g.Dictionary["errors"]["noItem"], name, g.CurrentRoom.Name)
+		return g.errorf("noItem", vars{"name": name})
 	}
 	if on == "" {
 		if item.Useable {
-			fmt.Println(item.UseString)
+			if !g.met(item.Conditions) {
+				return g.blocked(g.text(item.BlockedString))
+			}
+			fmt.Fprintln(screen, render("text", g.text(item.UseString)))
+			g.apply(item.Effects)
 			return nil
 		}
-		return fmt.Errorf(g.Dictionary["errors"]["itemNotUseable"])
+		return g.errorf("itemNotUseable")
 	}
 
 	itemOn := g.getItemByName(on)
 	if itemOn == nil {
-		exit := g.CurrentRoom.getExitByName(on)
+		exit := g.getExitByName(on)
 		if exit == nil {
-			return fmt.Errorf(g.Dictionary["errors"]["noItem"], on, g.CurrentRoom.Name)
+			return g.errorf("noItem", vars{"name": on})
 		}
 		return g.useOnExit(item, exit)
 	}
@@ -124,60 +429,71 @@ func (g *Game) use(name string, on string) error {
 }
 
 // useOnItem actions the use function of an item on another item.
+// Successful uses are declared as rules, see runRules. This reports why a use failed.
 func (g *Game) useOnItem(item *item, itemOn *item) error {
-	if !itemOn.Takeable && strings.ToLower(itemOn.TakeableWith) == strings.ToLower(item.Name) {
-		itemOn.Takeable = true
-		fmt.Print(itemOn.TakeableString)
-		fmt.Println()
-		g.take(itemOn.Name)
-		return nil
-	}
-	if itemOn.Locked && strings.ToLower(itemOn.UnlockedWith) == strings.ToLower(item.Name) {
-		itemOn.Locked = false
-		if itemOn.UnlockName != "" {
-			itemOn.Name = itemOn.UnlockName
-			itemOn.Description = itemOn.UnlockDescription
-		}
-		fmt.Print(itemOn.UnlockString)
-		fmt.Println()
-		g.open(itemOn.Name)
-		return nil
-	}
-	if itemOn.Takeable == false {
-		return fmt.Errorf(itemOn.NotTakeableString)
+	if itemOn.Takeable == false && itemOn.NotTakeableString != "" {
+		return errors.New(g.text(itemOn.NotTakeableString))
 	}
-
-	return fmt.Errorf(g.Dictionary["errors"]["cannotUseItem"], item.Name, itemOn.Name)
+	return g.errorf("cannotUseItem", vars{"item": g.named(item.ID, item.Name), "target": g.named(itemOn.ID, itemOn.Name)})
 }
 
 // useOnExit actions the use function of an item on an exit.
+// Successful uses are declared as rules, see runRules. This reports why a use failed.
 func (g *Game) useOnExit(item *item, exit *exit) error {
-	if exit.Locked && strings.ToLower(exit.UnlockedWith) == strings.ToLower(item.Name) {
-		g.unlockExit(exit)
-		return nil
+	return g.errorf("cannotUseItem", vars{"item": g.named(item.ID, item.Name), "target": g.named(exit.ID, exit.Name)})
+}
+
+// unlockItem unlocks an item, renaming it if it has an UnlockName.
+func (g *Game) unlockItem(item *item) {
+	unlocked := false
+	applyState(item, itemState{Name: item.UnlockName, Description: item.UnlockDescription, Locked: &unlocked})
+	if item.UnlockName != "" {
+		g.indexNames()
 	}
-	return fmt.Errorf(g.Dictionary["errors"]["cannotUseItem"], item.Name, exit.Name)
+	fmt.Fprint(screen, render("text", g.text(item.UnlockString)))
+	fmt.Fprintln(screen)
 }
 
 // unlockExit unlocks a matching exit.
 // Exits are not bi-directional. There is a separate exit object in the other room.
-// When an exit is unlocked from one room, it should unlock the exit in the other room too.
+// When an exit is unlocked from one room, it unlocks the exit in the other room too.
 func (g *Game) unlockExit(exit *exit) {
 	exit.Locked = false
-	room := g.getRoomByID(exit.RoomID)
-	for index, e := range room.Exits {
-		fmt.Println(e.Name, exit.Name)
-		if e.Name == exit.Name {
-			room.Exits[index].Locked = false
-		}
+	exit.rename()
+	g.syncExit(exit)
+	g.indexNames()
+	fmt.Fprintln(screen, render("text", g.text(exit.UnlockString)))
+	fmt.Fprintln(screen)
+}
+
+// rename applies the UnlockName and UnlockDescription of an exit, if it has them.
+func (e *exit) rename() {
+	if e.UnlockName != "" {
+		e.Name = e.UnlockName
+		e.Description = e.UnlockDescription
+	}
+}
+
+// syncExit copies the lock and open state of an exit onto the paired exit in the other room.
+// The paired exit is renamed along with it when it is unlocked.
+func (g *Game) syncExit(exit *exit) {
+	reverse := g.reverseExit(exit)
+	if reverse == nil {
+		return
+	}
+	if reverse.Locked && !exit.Locked {
+		reverse.rename()
 	}
-	if exit.UnlockName != "" {
-		exit.Name = exit.UnlockName
-		exit.Description = exit.UnlockDescription
+	reverse.Locked = exit.Locked
+	reverse.Closed = exit.Closed
+}
+
+// blocked returns the error shown when the conditions on an object are not met.
+func (g *Game) blocked(blockedString string) error {
+	if blockedString != "" {
+		return errors.New(blockedString)
 	}
-	fmt.Println(exit.UnlockString)
-	fmt.Println()
-	g.goDirection(exit.Direction)
+	return g.errorf("blocked")
 }
 
 // isNil is a helper function to determine if an interface is nil
@@ -185,16 +501,60 @@ func isNil(i interface{}) bool {
 	return i == nil || reflect.ValueOf(i).IsNil()
 }
 
-// help returns a list of ingame commands and shortcuts based on the Game Dictionary
+// customVerb actions a verb declared by the world in the Game Dictionary on a visible item or exit.
+// Objects without a response to the verb give the fallback "cantVerb" message.
+func (g *Game) customVerb(verb string, name string) error {
+	word := g.Dictionary["verbs"][verb]
+	if name == "" {
+		return g.errorf("verbWhat", vars{"verb": word})
+	}
+	var responses map[string]verbResponse
+	var object noun
+	if item := g.getItemByName(name); item != nil {
+		responses, object = item.Verbs, g.named(item.ID, item.Name)
+	} else if exit := g.getExitByName(name); exit != nil {
+		responses, object = exit.Verbs, g.named(exit.ID, exit.Name)
+	} else {
+		return g.errorf("noObject", vars{"name": name})
+	}
+	response, ok := responses[verb]
+	if !ok {
+		return g.errorf("cantVerb", vars{"verb": word, "item": object})
+	}
+	if !g.met(response.Conditions) {
+		return g.blocked(g.text(response.BlockedString))
+	}
+	fmt.Fprintln(screen, render("text", g.text(response.Text)))
+	g.apply(response.Effects)
+	return nil
+}
+
+// help returns a list of ingame commands, custom verbs and shortcuts based on the Game Dictionary
 func (g *Game) help() string {
-	//So the help options come out in the same order every time.
-	sortedKeys := sortedKeys(g.Dictionary["shortcuts"])
+	shortcuts := make(map[string]string)
+	for _, key := range sortedKeys(g.Dictionary["shortcuts"]) {
+		word := g.Dictionary["shortcuts"][key]
+		if shortcuts[word] != "" {
+			shortcuts[word] += ", "
+		}
+		shortcuts[word] += key
+	}
+	words := make(map[string]string)
+	for _, section := range []string{"commands", "verbs"} {
+		for _, word := range g.Dictionary[section] {
+			words[word] = word
+		}
+	}
 
-	helptext := "List of commands:"
-	for _, key := range sortedKeys {
-		value := g.Dictionary["shortcuts"][key]
-		helpstring := g.Dictionary["helptext"][value]
-		helptext += "\n" + key + ": " + value + ": " + helpstring
+	//So the help options come out in the same order every time.
+	helptext := g.str("helpTitle")
+	for _, word := range sortedKeys(words) {
+		helpstring := g.Dictionary["helptext"][word]
+		if shortcuts[word] != "" {
+			helptext += "\n" + shortcuts[word] + ": " + word + ": " + helpstring
+		} else {
+			helptext += "\n" + word + ": " + helpstring
+		}
 	}
 	return helptext
 }
@@ -3,38 +3,61 @@
 package textgame
 
 import (
-	"bufio"
+	"bytes"
 	"fmt"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"os"
-	"os/exec"
-	"runtime"
+	"strconv"
 	"strings"
 )
 
 // ConfDir is the directory where new game configurations are stored.
 const ConfDir = "conf/"
 
+// WorldFile is the language neutral world all new games are loaded from.
+const WorldFile = ConfDir + "world"
+
+// LangDir is the directory where the message catalog of each language is stored.
+const LangDir = ConfDir + "lang/"
+
+// defaultLanguage is the language of saved games that do not record one.
+const defaultLanguage = "en"
+
 // SaveDir is the directory where save games are stored.
 const SaveDir = "saves/"
 
-// ReadLanguages lists all languages provided by the Game configuration.
+// ReadLanguages lists all languages with a message catalog in the Game configuration.
 func ReadLanguages() []string {
-	files, err := ioutil.ReadDir(ConfDir)
+	files, err := ioutil.ReadDir(LangDir)
 	if err != nil {
-		fmt.Printf("No language files found.", err)
+		fmt.Fprintln(screen, "No language files found.", err)
 		os.Exit(1)
 	}
 	var langs []string
 	for _, f := range files {
-		langs = append(langs, strings.Replace(f.Name(), ".yaml", "", -1))
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".yaml") {
+			continue
+		}
+		langs = append(langs, strings.TrimSuffix(f.Name(), ".yaml"))
 	}
 	return langs
 }
 
-// LoadGameState restores a Game state from a file into memory.
-func LoadGameState(fileName string) (*Game, error) {
+// NewGame loads the world with the message catalog of a language to start a new game.
+func NewGame(lang string) (*Game, error) {
+	return loadGame(WorldFile, lang)
+}
+
+// LoadGameState restores a Game state from a file into memory. Saved games hold no text,
+// so they may be continued in any language. With no language, the one it was saved in is used.
+func LoadGameState(fileName string, lang string) (*Game, error) {
+	return loadGame(fileName, lang)
+}
+
+// loadGame loads a world or saved game and combines it with the message catalog of a language.
+// With no language, the language the game was saved in is used.
+func loadGame(fileName string, lang string) (*Game, error) {
 	path := fileName + ".yaml"
 	yamlFile, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -43,10 +66,29 @@ func LoadGameState(fileName string) (*Game, error) {
 	var game Game
 	err = yaml.Unmarshal(yamlFile, &game)
 	if err != nil {
-		fmt.Printf("Error parsing YAML file: %s\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("Error parsing YAML file %s: %s", path, err)
+	}
+	game.file = path
+	if lang == "" {
+		lang = game.Language
+	}
+	if lang == "" {
+		lang = defaultLanguage
+	}
+	err = game.loadCatalog(lang)
+	if err != nil {
+		return nil, err
+	}
+	err = game.loadScripts()
+	if err != nil {
+		return nil, err
+	}
+	game.assignIDs()
+	game.linkExits()
+	game.compileRules()
+	for _, problem := range game.sanityCheck() {
+		fmt.Fprintln(os.Stderr, "Warning:", problem)
 	}
-	game.sanityCheck()
 	game.initialiseGameState()
 	return &game, nil
 }
@@ -54,43 +96,144 @@ func LoadGameState(fileName string) (*Game, error) {
 // saveGameState saves a Game state to a file to be continued later.
 func saveGameState(g *Game, stateName string) error {
 	g.SavedGame = true
-	d, err := yaml.Marshal(g)
+	var d bytes.Buffer
+	encoder := yaml.NewEncoder(&d)
+	encoder.SetIndent(2)
+	err := encoder.Encode(g)
 	if err != nil {
-		fmt.Printf("Error parsing YAML file: %s\n", err)
+		fmt.Fprintf(screen, "Error parsing YAML file: %s\n", err)
 	}
 	path := SaveDir + stateName + ".yaml"
-	err = ioutil.WriteFile(path, d, 0644)
+	err = ioutil.WriteFile(path, d.Bytes(), 0644)
 	if err != nil {
 		return fmt.Errorf("Unable to write file %s", path)
 	}
-	fmt.Println(g.Dictionary["strings"]["saveSuccessful"])
+	fmt.Fprintln(screen, render("text", g.str("saveSuccessful")))
 	return nil
 }
 
+// assignIDs gives every item and exit without an ID in the yaml configuration an ID
+// derived from its name. IDs are unique across the whole game and never change once
+// assigned, so they are kept in save files.
+func (g *Game) assignIDs() {
+	used := make(map[string]bool)
+	g.eachObjectID(func(id *string, name string) {
+		used[*id] = true
+	})
+	g.eachObjectID(func(id *string, name string) {
+		if *id != "" {
+			return
+		}
+		base := toID(name)
+		*id = base
+		for n := 2; used[*id]; n++ {
+			*id = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[*id] = true
+	})
+}
+
+// eachObjectID calls fn with the ID and name of every item, exit and character in the game.
+func (g *Game) eachObjectID(fn func(id *string, name string)) {
+	walkItems(g.Player, func(i *item) {
+		fn(&i.ID, i.Name)
+	})
+	for r := range g.Rooms {
+		room := &g.Rooms[r]
+		walkItems(room, func(i *item) {
+			fn(&i.ID, i.Name)
+		})
+		for e := range room.Exits {
+			fn(&room.Exits[e].ID, room.Exits[e].Name)
+		}
+		for n := range room.NPCs {
+			fn(&room.NPCs[n].ID, room.NPCs[n].Name)
+			walkItems(&room.NPCs[n], func(i *item) {
+				fn(&i.ID, i.Name)
+			})
+		}
+	}
+}
+
+// linkExits pairs every exit with the exit leading back from its destination room.
+// Exits may declare a shared Door ID in the yaml configuration. Otherwise the pair is
+// derived from RoomID, when the destination room has exactly one exit leading back.
+func (g *Game) linkExits() {
+	for i := range g.Rooms {
+		room := &g.Rooms[i]
+		for j := range room.Exits {
+			exit := &room.Exits[j]
+			if exit.Door != "" || exit.OneWay {
+				continue
+			}
+			destination := g.getRoomByID(exit.RoomID)
+			if destination == nil {
+				continue
+			}
+			back := destination.exitsTo(room.ID)
+			if len(back) != 1 || back[0].Door != "" || back[0].OneWay {
+				continue
+			}
+			door := fmt.Sprintf("door-%d-%d", room.ID, destination.ID)
+			exit.Door = door
+			back[0].Door = door
+		}
+	}
+}
+
 // sanityCheck validates the game data for any obvious inconsitencies or errors.
-func (g *Game) sanityCheck() {
+// Returns a description of each problem found.
+func (g *Game) sanityCheck() []string {
+	problems := append(g.checkVariables(), g.checkRules()...)
+	problems = append(problems, g.checkScripts()...)
+	problems = append(problems, g.checkHidden()...)
+	problems = append(problems, g.checkEvents()...)
+	problems = append(problems, g.checkNPCs()...)
+	problems = append(problems, g.checkStates()...)
+	ids := make(map[string]bool)
+	g.eachObjectID(func(id *string, name string) {
+		if ids[*id] {
+			problems = append(problems, fmt.Sprintf("Duplicate ID %s used by %s", *id, name))
+		}
+		ids[*id] = true
+	})
+	for _, room := range g.Rooms {
+		for index, exit := range room.Exits {
+			if g.getRoomByID(exit.RoomID) == nil {
+				problems = append(problems, fmt.Sprintf("Invalid Room ID %d in exit %s of room %d", exit.RoomID, exit.ID, room.ID))
+				continue
+			}
+			if exit.OneWay {
+				continue
+			}
+			if g.reverseExit(&room.Exits[index]) == nil {
+				problems = append(problems, fmt.Sprintf("Exit %s of room %d has no paired exit leading back", exit.ID, room.ID))
+			}
+		}
+	}
 	//Exits
 	//Must have a name
 	//Must have a unique name per Room
 	//Must contain a direction
 	//Must contain a description
-	//Must contain a valid Room ID
 	//Rooms
 	//Must have a unique id
 	//Must have a name
 	//Must have a description
 	//Must not have multiple exits with the same 'direction'
 	//Items
-	//Must be unique through the entire game
 	//Must have a name
 	//Must have a description
 	//Cannot have the same name as a direction
 	//General
+	return problems
 }
 
 // setInitialState initialises the Game state with information that cannot
 // be provided by the yaml configuration file.
 func (g *Game) initialiseGameState() {
+	g.applyStates()
+	g.indexNames()
 	g.setCurrentRoom(g.getRoomByID(g.CurrentRoomID))
 }
 
@@ -104,156 +247,294 @@ func (g *Game) expandShortcut(word string) string {
 	return word
 }
 
-// expandDirection takes a user entered shortcut and expands it into the full game direction
-// using the Game Dictionary provided in the yaml configuration.
+// expandDirection takes a user entered direction or shortcut and converts it into the key of
+// the direction used by exits in the world file, using the Game Dictionary.
 func (g *Game) expandDirection(word string) string {
-	lookup := strings.ToLower(g.Dictionary["directions"][strings.ToLower(word)])
-	if lookup != "" {
-		return lookup
+	for _, id := range g.names.resolve(word) {
+		if strings.HasPrefix(id, directionPrefix) {
+			return strings.TrimPrefix(id, directionPrefix)
+		}
 	}
 	return word
 }
 
-// parseInput takes a user input and returns the command, Item and object
-//<Command> <Object> [on <Object>]. Object names may includes spaces.
-func (g *Game) parseInput(input string) (string, string, string, error) {
-	words := strings.Split(input, " ")
+// commandKey converts a user entered command into the key of the command or custom verb
+// in the Game Dictionary. Only the words of the game's language are commands, so returns an
+// empty key if it is neither.
+func (g *Game) commandKey(command string) string {
+	for _, section := range []string{"commands", "verbs"} {
+		for key, word := range g.Dictionary[section] {
+			if command == strings.ToLower(word) {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+// isCommand returns if a key is a command or custom verb in the Game Dictionary.
+func (g *Game) isCommand(key string) bool {
+	return g.Dictionary["commands"][key] != "" || g.Dictionary["verbs"][key] != ""
+}
+
+// splitCommand finds the command at the start of the user's words. Commands may be phrases of
+// several words such as "turn on", and the longest matching phrase is preferred.
+// Returns the command key and the remaining words.
+func (g *Game) splitCommand(words []string) (string, []string) {
+	for n := len(words); n > 1; n-- {
+		key := g.commandKey(strings.ToLower(strings.Join(words[:n], " ")))
+		if g.isCommand(key) {
+			return key, words[n:]
+		}
+	}
+	return g.commandKey(strings.ToLower(g.expandShortcut(words[0]))), words[1:]
+}
+
+// targetCommands lists the game commands that accept a second object, joined to the
+// first by one of the prepositions in the Game Dictionary.
+var targetCommands = []string{"use", "put", "lock", "unlock", "ask", "give"}
+
+// takesTarget returns if a command accepts a second object.
+func takesTarget(command string) bool {
+	for _, key := range targetCommands {
+		if command == key {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTarget splits an object phrase on the first preposition found in the Game Dictionary.
+// Returns the object, the preposition key and the target object.
+func (g *Game) splitTarget(object string) (string, string, string) {
+	words := strings.Split(object, " ")
+	for i := 1; i < len(words)-1; i++ {
+		for key, preposition := range g.Dictionary["prepositions"] {
+			if words[i] == strings.ToLower(preposition) {
+				return strings.Join(words[:i], " "), key, strings.Join(words[i+1:], " ")
+			}
+		}
+	}
+	return object, "", ""
+}
+
+// parseInput takes a user input and returns the command key, object, preposition and target object.
+//<Command> <Object> [<Preposition> <Object>]. Object names may includes spaces.
+func (g *Game) parseInput(input string) (string, string, string, string, error) {
+	words := strings.Fields(input)
 	if len(words) == 0 {
-		return "", "", "", fmt.Errorf(g.Dictionary["errors"]["invalidCommand"], input)
+		return "", "", "", "", g.errorf("invalidCommand", vars{"input": input})
 	}
-	command := g.expandShortcut(words[0])
+	command, words := g.splitCommand(words)
 
 	var object string
+	var preposition string
 	var objectTarget string
-	if len(words) > 1 {
-		object = strings.ToLower(strings.Join(words[1:], " "))
-		if len(words) >= 4 && command == strings.ToLower(g.Dictionary["commands"]["use"]) {
-			objects := strings.Split(object, " on ")
-			if len(objects) != 2 {
-				return "", "", "", fmt.Errorf(g.Dictionary["errors"]["invalidCommand"], input)
-			}
-			object = objects[0]
-			objectTarget = objects[1]
+	if len(words) > 0 {
+		object = strings.ToLower(strings.Join(words, " "))
+		if takesTarget(command) {
+			object, preposition, objectTarget = g.splitTarget(object)
 		}
-		if command == strings.ToLower(g.Dictionary["commands"]["go"]) ||
-			command == strings.ToLower(g.Dictionary["commands"]["examine"]) {
+		if command == "go" || command == "examine" {
 			object = g.expandDirection(object)
 		}
 	}
-	return command, object, objectTarget, nil
+	return command, object, preposition, objectTarget, nil
 }
 
 // updateGameState updates the game state with user provided input.
+// Commands that succeed take a turn, unless they only report on or save the game.
 func (g *Game) updateGameState(input string) (*Game, error) {
-	command, object, objectTarget, err := g.parseInput(input)
+	//Numbers reply to the character the player is talking to, anything else ends the conversation
+	if g.inConversation() {
+		if _, err := strconv.Atoi(input); err == nil {
+			err = g.choose(input)
+			if err != nil {
+				return g, err
+			}
+			return g, g.tick()
+		}
+	}
+	g.Conversation = conversation{}
+	command, object, preposition, objectTarget, err := g.parseInput(input)
 	if err != nil {
 		return g, err
 	}
+	g, err = g.runCommand(input, command, object, preposition, objectTarget)
+	if err != nil || !takesTurn(command) {
+		return g, err
+	}
+	return g, g.tick()
+}
 
+// runCommand carries out a parsed command. Rules and scripts are checked before the game commands.
+func (g *Game) runCommand(input string, command string, object string, preposition string, objectTarget string) (*Game, error) {
 	g.DisplayRoomInfo = false
 	g.DisplayItemInfo = false
+	handled, err := g.runRules(command, object, objectTarget)
+	if handled {
+		return g, err
+	}
+	handled, err = g.runScripts(command, object, objectTarget)
+	if handled {
+		return g, err
+	}
+	handled, err = g.runTransitions(command, object, objectTarget)
+	if handled {
+		return g, err
+	}
 	switch command {
-	case strings.ToLower(g.Dictionary["commands"]["go"]):
+	case "go":
 		return g, g.goDirection(object)
-	case strings.ToLower(g.Dictionary["commands"]["examine"]):
+	case "examine":
 		return g, g.examine(object)
-	case strings.ToLower(g.Dictionary["commands"]["refresh"]):
-		fmt.Println(g.Dictionary["strings"]["refreshing"])
+	case "refresh":
+		fmt.Fprintln(screen, render("text", g.str("refreshing")))
 		g.displayRoomInfo()
 		return g, nil
-	case strings.ToLower(g.Dictionary["commands"]["inventory"]):
-		fmt.Println(g.Dictionary["strings"]["inventory"] + g.Player.getItemOptions())
+	case "inventory":
+		fmt.Fprintln(screen, render("label", g.str("inventory"))+g.getItemOptions(g.Player))
 		return g, nil
-	case strings.ToLower(g.Dictionary["commands"]["help"]):
-		fmt.Println(g.help())
+	case "help":
+		fmt.Fprintln(screen, g.help())
 		return g, nil
-	case strings.ToLower(g.Dictionary["commands"]["save"]):
+	case "time":
+		g.showTime()
+		return g, nil
+	case "language":
+		return g, g.setLanguage(object)
+	case "save":
 		return g, saveGameState(g, object)
-	case strings.ToLower(g.Dictionary["commands"]["load"]):
-		g, err := LoadGameState(SaveDir + object)
-		if err == nil {
-			fmt.Println(g.Dictionary["strings"]["loadSuccessful"])
+	case "load":
+		loaded, err := LoadGameState(SaveDir+object, g.Language)
+		if err != nil {
+			return g, err
 		}
-		return g, err
-	case strings.ToLower(g.Dictionary["commands"]["quit"]):
-		os.Exit(1)
-	case strings.ToLower(g.Dictionary["commands"]["open"]):
+		fmt.Fprintln(screen, render("text", loaded.str("loadSuccessful")))
+		return loaded, nil
+	case "quit":
+		g.Over = true
+	case "open":
 		return g, g.open(object)
-	case strings.ToLower(g.Dictionary["commands"]["take"]):
+	case "take":
 		return g, g.take(object)
-	case strings.ToLower(g.Dictionary["commands"]["use"]):
+	case "use":
 		return g, g.use(object, objectTarget)
+	case "close":
+		return g, g.close(object)
+	case "lock":
+		return g, g.lock(object, objectTarget)
+	case "unlock":
+		return g, g.unlock(object, objectTarget)
+	case "search":
+		return g, g.search(object)
+	case "turnon":
+		return g, g.turnOn(object)
+	case "turnoff":
+		return g, g.turnOff(object)
+	case "talk":
+		return g, g.talk(object)
+	case "ask":
+		return g, g.ask(object, objectTarget)
+	case "give":
+		return g, g.give(object, objectTarget)
+	case "drop":
+		return g, g.drop(object)
+	case "put":
+		return g, g.put(object, preposition, objectTarget)
 	default:
-		return g, fmt.Errorf(g.Dictionary["errors"]["invalidCommand"], input)
+		if g.Dictionary["verbs"][command] != "" {
+			return g, g.customVerb(command, object)
+		}
+		return g, g.errorf("invalidCommand", vars{"input": input})
 	}
 	return g, nil
 }
 
 // Play contains the game logic and game loop for playing the textgame.
-// Bug(wilcox-liam): Is replacing the game from within the game loop super weird?
 func (g *Game) Play() {
+	g.run(lineFrontEnd{})
+}
+
+// run plays the game with a front end until it is over.
+// Bug(wilcox-liam): Is replacing the game from within the game loop super weird?
+func (g *Game) run(f frontEnd) {
+	f.open()
+	defer f.close()
 	//Do not display the welcome text if loading a saved game
 	if g.SavedGame == false {
-		fmt.Println(fmt.Errorf(g.Dictionary["strings"]["welcome"], g.Player.Name, g.Name))
-		fmt.Println()
-		fmt.Println(g.Dictionary["strings"]["helpAdvice"])
-		fmt.Println()
-		fmt.Println(g.Name)
-		fmt.Println()
-		fmt.Println(g.Description)
-	}
-
-	var err error
-	reader := bufio.NewReader(os.Stdin)
-	//Break Loop when ring is found
-	loop := true
-	for loop {
-		if g.DisplayRoomInfo {
-			fmt.Println(g.CurrentRoom.Name)
-			fmt.Println()
-			fmt.Println(g.CurrentRoom.Description)
+		fmt.Fprintln(screen, render("text", g.str("welcome")))
+		fmt.Fprintln(screen)
+		fmt.Fprintln(screen, render("text", g.str("helpAdvice")))
+		fmt.Fprintln(screen)
+		fmt.Fprintln(screen, render("title", g.text(g.Name)))
+		fmt.Fprintln(screen)
+		fmt.Fprintln(screen, render("story", g.text(g.Description)))
+	}
+
+	//Break Loop when the WinItem is found, a rule ends the game or there are no more commands
+	for !g.Over {
+		f.show(g)
+		line, err := f.command(g)
+		if err != nil {
+			return
 		}
-		if g.DisplayItemInfo {
-			fmt.Println(g.Dictionary["strings"]["directions"] + g.CurrentRoom.getDirections())
-			fmt.Println(g.Dictionary["strings"]["exits"] + g.CurrentRoom.getExitOptions())
-			fmt.Println(g.Dictionary["strings"]["items"] + g.CurrentRoom.getItemOptions())
-			fmt.Println(g.Dictionary["strings"]["inventory"] + g.Player.getItemOptions())
-			fmt.Println()
+		g, err = g.updateGameState(line)
+		if err != nil {
+			fmt.Fprint(screen, render("error", err.Error()))
 		}
+		fmt.Fprintln(screen)
+		if g.WinItem != "" && getItemByID(toID(g.WinItem), g.Player) != nil {
+			g.Over = true
+		}
+	}
+}
 
-		fmt.Print(g.Dictionary["strings"]["command"])
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(input)
-		fmt.Println()
-		g, err = g.updateGameState(input)
-		if err != nil {
-			fmt.Print(err)
+// showRoom writes the name and description of the current room when the player enters it,
+// and with lists, what can be seen in it when that changes.
+func (g *Game) showRoom(lists bool) {
+	if g.DisplayRoomInfo {
+		fmt.Fprintln(screen, render("room", g.text(g.CurrentRoom.Name)))
+		fmt.Fprintln(screen)
+		//Nothing in a dark room can be seen but the player's inventory
+		if g.isLit(g.CurrentRoom) {
+			fmt.Fprintln(screen, render("description", g.text(g.CurrentRoom.Description)))
+		} else {
+			fmt.Fprintln(screen, render("description", g.darkString(g.CurrentRoom)))
 		}
-		fmt.Println()
-		item := g.Player.getItemByName("Anillo con Promiso")
-		if item != nil {
-			loop = false
+	}
+	if lists && g.DisplayItemInfo {
+		for _, l := range g.roomLists() {
+			fmt.Fprintln(screen, render("label", l.label)+l.options)
 		}
+		fmt.Fprintln(screen)
 	}
 }
 
-// CallClear is a helper function to clear the command prompt in different Operating Systems.
-func CallClear() {
-	clear := make(map[string]func()) //Initialize it
-	clear["linux"] = func() {
-		cmd := exec.Command("clear") //Linux example, its tested
-		cmd.Stdout = os.Stdout
-		cmd.Run()
-	}
-	clear["windows"] = func() {
-		cmd := exec.Command("cmd", "/c", "cls") //Windows example, its tested
-		cmd.Stdout = os.Stdout
-		cmd.Run()
-	}
-	value, ok := clear[runtime.GOOS] //runtime.GOOS -> linux, windows, darwin etc.
-	if ok {                          //if we defined a clear func for that platform:
-		value() //we execute it
-	} else { //unsupported platform
-		panic("Your platform is unsupported! I can't clear terminal screen :(" + runtime.GOOS)
+// listing is a list of the directions, exits, items or characters that can be seen, or the
+// inventory, with its label.
+type listing struct {
+	label   string
+	options string
+}
+
+// roomLists returns the lists of what can be seen in the current room, and the inventory.
+// Nothing in a dark room can be seen but the player's inventory.
+func (g *Game) roomLists() []listing {
+	var lists []listing
+	if g.isLit(g.CurrentRoom) {
+		lists = append(lists,
+			listing{g.str("directions"), g.getDirections(g.CurrentRoom)},
+			listing{g.str("exits"), g.getExitOptions(g.CurrentRoom)},
+			listing{g.str("items"), g.getItemOptions(g.CurrentRoom)})
+		if len(g.CurrentRoom.NPCs) > 0 {
+			lists = append(lists, listing{g.str("characters"), g.getNPCOptions(g.CurrentRoom)})
+		}
 	}
+	return append(lists, listing{g.str("inventory"), g.getItemOptions(g.Player)})
+}
+
+// CallClear clears the screen, as chosen with SetClear.
+func CallClear() {
+	screen.clear()
 }
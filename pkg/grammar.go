@@ -0,0 +1,222 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// grammar describes how the objects of the world are spoken of in one language.
+// Gender is the gender of every object that does not set its own.
+// Articles are the definite and indefinite articles for each gender, or gender and number,
+// such as "feminine" or "feminine-plural". Objects hold the grammar of each object by ID.
+// Pronouns are the sets of pronouns the player may choose from.
+type grammar struct {
+	Gender   string
+	Articles map[string]articles
+	Objects  map[string]nounGrammar
+	Pronouns map[string]pronounSet
+}
+
+// articles are the definite and indefinite articles used with a noun.
+type articles struct {
+	Definite   string
+	Indefinite string
+}
+
+// nounGrammar is the grammar of the name of one object in a language.
+// Plural names, such as "Shoes", are plural however many there are. Uncountable names,
+// such as "Water", and Proper names, such as "Cassandra", take different or no articles.
+// Definite and Indefinite replace the articles of the language for this name.
+type nounGrammar struct {
+	Gender      string
+	Plural      bool
+	Uncountable bool
+	Proper      bool
+	Definite    string
+	Indefinite  string
+}
+
+// noun is an object named in a message. Besides its name, a template may ask for its article
+// {item.definite} or {item.indefinite}, capitalised as {item.Definite}, its {item.gender}, and
+// select on its gender or number. The player is a noun too, with their pronouns
+// {player.subject}, {player.object}, {player.possessive} and {player.reflexive}. Articles are
+// followed by a space, unless they end in an apostrophe, so are written next to the name:
+// "{item.definite}{item}".
+type noun struct {
+	ID   string
+	Name string
+}
+
+// String returns the name of the noun, so nouns print as their name.
+func (n noun) String() string {
+	return n.Name
+}
+
+// named returns a noun for an object with an ID and the key of its name.
+func (g *Game) named(id string, name string) noun {
+	return noun{id, g.text(name)}
+}
+
+// nounGrammar returns the grammar of a noun in the game's language, filling in the gender
+// and articles the language gives it by default.
+func (g *Game) nounGrammar(n noun) nounGrammar {
+	ng := g.grammar.Objects[n.ID]
+	if n.ID == playerID {
+		p, _ := g.pronounSet(g.Player.Pronouns)
+		ng = nounGrammar{Gender: p.Gender, Plural: p.Plural, Proper: true}
+	}
+	if ng.Gender == "" {
+		ng.Gender = g.grammar.Gender
+	}
+	if ng.Proper {
+		return ng
+	}
+	form := ng.Gender
+	switch {
+	case ng.Plural:
+		form += "-plural"
+	case ng.Uncountable:
+		form += "-uncountable"
+	}
+	a, ok := g.grammar.Articles[form]
+	if !ok {
+		a = g.grammar.Articles[ng.Gender]
+	}
+	if ng.Definite == "" {
+		ng.Definite = a.Definite
+	}
+	if ng.Indefinite == "" {
+		ng.Indefinite = a.Indefinite
+	}
+	return ng
+}
+
+// attribute returns a grammatical attribute of a noun named in a template.
+func (g *Game) attribute(n noun, name string) (string, bool) {
+	ng := g.nounGrammar(n)
+	var value string
+	switch strings.ToLower(name) {
+	case "definite":
+		value = ng.Definite
+	case "indefinite":
+		value = ng.Indefinite
+	case "gender":
+		value = ng.Gender
+	case "subject", "object", "possessive", "reflexive":
+		if n.ID != playerID {
+			return "", false
+		}
+		p, _ := g.pronounSet(g.Player.Pronouns)
+		value = map[string]string{"subject": p.Subject, "object": p.Object, "possessive": p.Possessive,
+			"reflexive": p.Reflexive}[strings.ToLower(name)]
+	default:
+		return "", false
+	}
+	if name != strings.ToLower(name) {
+		value = capitalise(value)
+	}
+	if value != "" && (strings.ToLower(name) == "definite" || strings.ToLower(name) == "indefinite") &&
+		!strings.HasSuffix(value, "'") {
+		value += " "
+	}
+	return value, true
+}
+
+// capitalise returns a string with its first letter in upper case.
+func capitalise(s string) string {
+	for i, r := range s {
+		return strings.ToUpper(string(r)) + s[i+len(string(r)):]
+	}
+	return s
+}
+
+// pluralCategory returns the plural category of a number in the game's language. In the game's
+// languages only 1 is singular ("one") and every other number is "other".
+func (g *Game) pluralCategory(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// count returns the number a plural selects on. Nouns count as 1, or 2 if their name is plural.
+func (g *Game) count(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case noun:
+		if g.nounGrammar(v).Plural {
+			return 2, true
+		}
+		return 1, true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// selectCase fills a select or plural placeholder in the style of ICU MessageFormat:
+// {item, select, feminine {abierta} other {abierto}} selects on the gender of a noun, or any
+// other value. A plural noun selects a case such as "feminine-plural" before "feminine".
+// {count, plural, =0 {none} one {# item} other {# items}} selects on a number, or the number
+// of a noun, with # standing for the number. The case "other" is used when no other matches.
+func (g *Game) selectCase(value interface{}, kind string, cases string, v vars) (string, bool) {
+	options := parseCases(cases)
+	var text, number string
+	var ok bool
+	switch kind {
+	case "select":
+		if n, isNoun := value.(noun); isNoun {
+			ng := g.nounGrammar(n)
+			if ng.Plural {
+				text, ok = options[ng.Gender+"-plural"]
+			}
+			if !ok {
+				text, ok = options[ng.Gender]
+			}
+		} else {
+			text, ok = options[fmt.Sprint(value)]
+		}
+	case "plural":
+		n, isNumber := g.count(value)
+		if !isNumber {
+			return "", false
+		}
+		if text, ok = options["="+strconv.Itoa(n)]; !ok {
+			text, ok = options[g.pluralCategory(n)]
+		}
+		number = strconv.Itoa(n)
+	default:
+		return "", false
+	}
+	if !ok {
+		text = options["other"]
+	}
+	if number != "" {
+		text = strings.Replace(text, "#", number, -1)
+	}
+	return g.expand(text, v), true
+}
+
+// parseCases splits the cases of a select or plural placeholder, "key {text} key {text}",
+// into the text of each key.
+func parseCases(cases string) map[string]string {
+	options := map[string]string{}
+	for {
+		cases = strings.TrimSpace(cases)
+		open := strings.IndexByte(cases, '{')
+		if open < 0 {
+			return options
+		}
+		end := closingBrace(cases, open)
+		if end < 0 {
+			return options
+		}
+		options[strings.TrimSpace(cases[:open])] = cases[open+1 : end]
+		cases = cases[end+1:]
+	}
+}
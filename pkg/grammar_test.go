@@ -0,0 +1,150 @@
+package textgame
+
+import "testing"
+
+// englishGrammar and spanishGrammar follow the grammar sections of the game's catalogs.
+var englishGrammar = grammar{
+	Gender: "neuter",
+	Articles: map[string]articles{
+		"neuter":             {Definite: "the", Indefinite: "a"},
+		"neuter-plural":      {Definite: "the", Indefinite: "some"},
+		"neuter-uncountable": {Definite: "the", Indefinite: "some"},
+	},
+	Objects: map[string]nounGrammar{
+		"shoes":  {Plural: true},
+		"water":  {Uncountable: true},
+		"apple":  {Indefinite: "an"},
+		"cassie": {Proper: true},
+	},
+	Pronouns: map[string]pronounSet{
+		"she":  {Name: "she", Gender: "feminine", Subject: "she", Object: "her", Possessive: "her", Reflexive: "herself"},
+		"they": {Name: "they", Gender: "neuter", Plural: true, Subject: "they", Object: "them", Possessive: "their", Reflexive: "themself"},
+	},
+}
+
+var spanishGrammar = grammar{
+	Gender: "masculine",
+	Articles: map[string]articles{
+		"masculine":        {Definite: "el", Indefinite: "un"},
+		"masculine-plural": {Definite: "los", Indefinite: "unos"},
+		"feminine":         {Definite: "la", Indefinite: "una"},
+		"feminine-plural":  {Definite: "las", Indefinite: "unas"},
+	},
+	Objects: map[string]nounGrammar{
+		"guitar":   {Gender: "feminine"},
+		"shoes":    {Plural: true},
+		"glasses":  {Gender: "feminine", Plural: true},
+		"propoleo": {Proper: true},
+		"tree":     {Definite: "l'"},
+	},
+	Pronouns: map[string]pronounSet{
+		"she":  {Name: "ella", Gender: "feminine", Subject: "ella", Object: "la", Possessive: "su", Reflexive: "se"},
+		"they": {Name: "elle", Gender: "neuter", Subject: "elle", Object: "le", Possessive: "su", Reflexive: "se"},
+	},
+}
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "other"},
+		{1, "one"},
+		{2, "other"},
+		{5, "other"},
+		{21, "other"},
+		{-1, "other"},
+	}
+	for _, lang := range []string{"en", "es"} {
+		g := &Game{Language: lang}
+		for _, test := range tests {
+			if got := g.pluralCategory(test.n); got != test.want {
+				t.Errorf("%s: pluralCategory(%d) = %s, want %s", lang, test.n, got, test.want)
+			}
+		}
+	}
+}
+
+func TestNounAttributes(t *testing.T) {
+	tests := []struct {
+		lang     string
+		template string
+		item     noun
+		pronouns string
+		want     string
+	}{
+		{"en", "{item.definite}{item}", noun{"box", "Box"}, "she", "the Box"},
+		{"en", "{item.Indefinite}{item}", noun{"box", "Box"}, "she", "A Box"},
+		{"en", "{item.indefinite}{item}", noun{"apple", "Apple"}, "she", "an Apple"},
+		{"en", "{item.indefinite}{item}", noun{"shoes", "Shoes"}, "she", "some Shoes"},
+		{"en", "{item.indefinite}{item}", noun{"water", "Water"}, "she", "some Water"},
+		{"en", "{item.definite}{item}", noun{"cassie", "Cassie"}, "she", "Cassie"},
+		{"en", "{item.gender}", noun{"box", "Box"}, "she", "neuter"},
+		{"en", "{player.Subject} {player.reflexive}", noun{}, "they", "They themself"},
+		{"en", "{item.subject}", noun{"box", "Box"}, "she", "{item.subject}"},
+		{"es", "{item.definite}{item}", noun{"guitar", "Guitarra"}, "she", "la Guitarra"},
+		{"es", "{item.Indefinite}{item}", noun{"shoes", "Zapatos"}, "she", "Unos Zapatos"},
+		{"es", "{item.definite}{item}", noun{"glasses", "Copas"}, "she", "las Copas"},
+		{"es", "{item.definite}{item}", noun{"propoleo", "Propóleo"}, "she", "Propóleo"},
+		{"es", "{item.definite}{item}", noun{"tree", "Arbre"}, "she", "l'Arbre"},
+		{"es", "{player.subject}", noun{}, "she", "ella"},
+	}
+	for _, test := range tests {
+		g := &Game{Language: test.lang, Player: &player{Name: "Ana", Pronouns: test.pronouns}, grammar: englishGrammar}
+		if test.lang == "es" {
+			g.grammar = spanishGrammar
+		}
+		if got := g.expand(test.template, vars{"item": test.item}); got != test.want {
+			t.Errorf("%s: expand(%q) with %v = %q, want %q", test.lang, test.template, test.item, got, test.want)
+		}
+	}
+}
+
+func TestSelectCase(t *testing.T) {
+	const open = "{item, select, feminine-plural {abiertas} feminine {abierta} masculine-plural {abiertos} other {abierto}}"
+	const is = "{item, plural, one {está} other {están}}"
+	const count = "{n, plural, =0 {ninguno} one {# objeto} other {# objetos}}"
+	const welcome = "{player, select, feminine {bienvenida} masculine {bienvenido} other {bienvenide}}"
+	tests := []struct {
+		lang     string
+		template string
+		v        vars
+		want     string
+	}{
+		// Select on the gender and number of a noun, falling back to other.
+		{"es", open, vars{"item": noun{"guitar", "Guitarra"}}, "abierta"},
+		{"es", open, vars{"item": noun{"glasses", "Copas"}}, "abiertas"},
+		{"es", open, vars{"item": noun{"shoes", "Zapatos"}}, "abiertos"},
+		{"es", open, vars{"item": noun{"box", "Caja"}}, "abierto"},
+		{"es", "{item, select, feminine {abierta} other {abierto}}", vars{"item": noun{"glasses", "Copas"}}, "abierta"},
+		{"es", welcome, vars{}, "bienvenida"},
+		{"es", "{mood, select, happy {:)} other {:|}}", vars{"mood": "sad"}, ":|"},
+		{"es", "{mood, select, happy {:)} other {:|}}", vars{"mood": "happy"}, ":)"},
+
+		// Plural on a number, or the number of a noun.
+		{"es", is, vars{"item": noun{"guitar", "Guitarra"}}, "está"},
+		{"es", is, vars{"item": noun{"shoes", "Zapatos"}}, "están"},
+		{"en", "{item, plural, one {is} other {are}}", vars{"item": noun{"shoes", "Shoes"}}, "are"},
+		{"es", count, vars{"n": 0}, "ninguno"},
+		{"es", count, vars{"n": 1}, "1 objeto"},
+		{"es", count, vars{"n": 3}, "3 objetos"},
+		{"en", "{n, plural, one {# item} other {# items}}", vars{"n": "2"}, "2 items"},
+		{"en", "{n, plural, one {# item} other {# items}}", vars{"n": 0}, "0 items"},
+
+		// A missing case with no other case is left empty.
+		{"en", "[{n, plural, one {# item}}]", vars{"n": 3}, "[]"},
+		{"es", "[{item, select, feminine {abierta}}]", vars{"item": noun{"box", "Caja"}}, "[]"},
+
+		// A plural of a value that is not a number is left as it is written.
+		{"en", "{mood, plural, other {x}}", vars{"mood": "sad"}, "{mood, plural, other {x}}"},
+	}
+	for _, test := range tests {
+		g := &Game{Language: test.lang, Player: &player{Name: "Ana", Pronouns: "she"}, grammar: englishGrammar}
+		if test.lang == "es" {
+			g.grammar = spanishGrammar
+		}
+		if got := g.expand(test.template, test.v); got != test.want {
+			t.Errorf("%s: expand(%q, %v) = %q, want %q", test.lang, test.template, test.v, got, test.want)
+		}
+	}
+}
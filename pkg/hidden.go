@@ -0,0 +1,116 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"strings"
+)
+
+// revealedBy returns if a hidden object is revealed by examining or searching another object.
+// Objects with no RevealedBy are revealed by searching the room they are in, which is passed as
+// an empty id.
+func revealedBy(refs []string, id string) bool {
+	if len(refs) == 0 {
+		return id == ""
+	}
+	for _, ref := range refs {
+		if toID(ref) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// revealBy reveals every hidden item and exit in the current room or inventory that is revealed by
+// examining or searching the object with an id. An empty id is a search of the room itself.
+// Nothing is found in a dark room. Returns the number of objects revealed.
+func (g *Game) revealBy(id string) int {
+	found := 0
+	if !g.isLit(g.CurrentRoom) {
+		return found
+	}
+	check := func(i *item) {
+		if i.Hidden && revealedBy(i.RevealedBy, id) {
+			i.Hidden = false
+			found++
+			g.printReveal(i.RevealString, g.named(i.ID, i.Name))
+		}
+	}
+	walkVisibleItems(g.CurrentRoom, check)
+	if id != "" {
+		walkVisibleItems(g.Player, check)
+	}
+	for index := range g.CurrentRoom.Exits {
+		exit := &g.CurrentRoom.Exits[index]
+		if exit.Hidden && revealedBy(exit.RevealedBy, id) {
+			exit.Hidden = false
+			found++
+			g.printReveal(exit.RevealString, g.named(exit.ID, exit.Name))
+		}
+	}
+	if found > 0 {
+		g.DisplayItemInfo = true
+	}
+	return found
+}
+
+// printReveal prints the message shown when a hidden object is found.
+func (g *Game) printReveal(revealString string, found noun) {
+	if revealString != "" {
+		fmt.Fprintln(screen, render("text", g.text(revealString)))
+		return
+	}
+	fmt.Fprint(screen, render("text", g.str("revealed", vars{"item": found})))
+	fmt.Fprintln(screen)
+}
+
+// checkHidden confirms every hidden object can be revealed and returns a description of each
+// problem found. Hidden objects may be revealed by another object, by searching the room they
+// are in, or by a rule or script.
+func (g *Game) checkHidden() []string {
+	var problems []string
+	revealable := func(id string, refs []string, inRoom bool) bool {
+		if len(refs) == 0 && inRoom {
+			return true
+		}
+		for _, ref := range refs {
+			if g.itemByID(toID(ref)) != nil || g.exitByID(toID(ref)) != nil {
+				return true
+			}
+		}
+		for _, r := range g.Rules {
+			for _, a := range r.Do {
+				if toID(a.Reveal) == id {
+					return true
+				}
+			}
+		}
+		for _, source := range g.allScripts() {
+			if strings.Contains(source.Source, "\""+id+"\"") {
+				return true
+			}
+		}
+		return false
+	}
+	check := func(inRoom bool) func(i *item) {
+		return func(i *item) {
+			if i.Hidden && !revealable(i.ID, i.RevealedBy, inRoom) {
+				problems = append(problems, fmt.Sprintf("Hidden item %s can never be revealed", i.ID))
+			}
+		}
+	}
+	walkItems(g.Player, check(false))
+	for r := range g.Rooms {
+		walkItems(&g.Rooms[r], check(true))
+		for n := range g.Rooms[r].NPCs {
+			walkItems(&g.Rooms[r].NPCs[n], check(false))
+		}
+		for _, exit := range g.Rooms[r].Exits {
+			if exit.Hidden && !revealable(exit.ID, exit.RevealedBy, true) {
+				problems = append(problems, fmt.Sprintf("Hidden exit %s can never be revealed", exit.ID))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,339 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+// catalog holds the text of a game in one language: the Game Dictionary, the messages
+// referred to by key from the world file and the grammar of the objects they name.
+type catalog struct {
+	Dictionary map[string]map[string]string
+	Messages   map[string]string
+	Grammar    grammar
+}
+
+// fixedSections are the sections of the Game Dictionary whose keys are the same in every language.
+// The keys of the other sections, such as shortcuts and aliases, are words of the language itself.
+var fixedSections = []string{"commands", "verbs", "directions", "prepositions", "strings", "errors"}
+
+// logger reports problems found while playing, such as missing translations. It is silent
+// unless a log is set with SetLog.
+var logger = log.New(ioutil.Discard, "", log.LstdFlags)
+
+// SetLog writes warnings about the game, such as missing translations, to w.
+func SetLog(w io.Writer) {
+	logger.SetOutput(w)
+}
+
+// readCatalog reads the message catalog of a language.
+func readCatalog(lang string) (*catalog, error) {
+	path := LangDir + lang + ".yaml"
+	yamlFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find language file %s", path)
+	}
+	var c catalog
+	err = yaml.Unmarshal(yamlFile, &c)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing YAML file %s: %s", path, err)
+	}
+	return &c, nil
+}
+
+// loadCatalog reads the message catalog of a language and uses it for the game's text.
+// Text missing from any other language falls back to the default language.
+func (g *Game) loadCatalog(lang string) error {
+	c, err := readCatalog(lang)
+	if err != nil {
+		return err
+	}
+	g.base = nil
+	if lang != defaultLanguage {
+		g.base, err = readCatalog(defaultLanguage)
+		if err != nil {
+			return err
+		}
+	}
+	g.Language = lang
+	g.Dictionary = c.Dictionary
+	g.messages = c.Messages
+	g.grammar = c.Grammar
+	screen.more = g.str("more")
+	return nil
+}
+
+// setLanguage switches the game's text and vocabulary to another language, keeping the state
+// of the game as it is.
+func (g *Game) setLanguage(lang string) error {
+	languages := ReadLanguages()
+	if !containsID(languages, lang) {
+		return g.errorf("unknownLanguage", vars{"language": lang, "languages": strings.Join(languages, ", ")})
+	}
+	err := g.loadCatalog(lang)
+	if err != nil {
+		return err
+	}
+	g.indexNames()
+	g.displayRoomInfo()
+	return nil
+}
+
+// entry returns an entry of the Game Dictionary, falling back to the default language.
+// Returns false if neither language has the entry.
+func (g *Game) entry(section string, key string) (string, bool) {
+	if s, ok := g.Dictionary[section][key]; ok {
+		return s, true
+	}
+	if g.base != nil {
+		if s, ok := g.base.Dictionary[section][key]; ok {
+			logger.Printf("%s: missing %s %s, using %s", g.Language, section, key, defaultLanguage)
+			return s, true
+		}
+	}
+	logger.Printf("%s: missing %s %s", g.Language, section, key)
+	return "", false
+}
+
+// format fills the placeholders of an entry of the Game Dictionary with the values provided.
+// An entry missing from every language is shown as its key, rather than a blank line.
+func (g *Game) format(section string, key string, values []vars) string {
+	template, ok := g.entry(section, key)
+	if !ok {
+		return key
+	}
+	v := vars{}
+	for _, values := range values {
+		for name, value := range values {
+			v[name] = value
+		}
+	}
+	return g.expand(template, v)
+}
+
+// str returns a string from the Game Dictionary, filling its placeholders with the values provided.
+func (g *Game) str(key string, v ...vars) string {
+	return g.format("strings", key, v)
+}
+
+// errorf returns an error from the Game Dictionary, filling its placeholders with the values provided.
+func (g *Game) errorf(key string, v ...vars) error {
+	return errors.New(g.format("errors", key, v))
+}
+
+// text returns the message for a key from the language catalog with its placeholders filled.
+func (g *Game) text(key string) string {
+	return g.expand(g.message(key), nil)
+}
+
+// message returns the message for a key from the language catalog, falling back to the default
+// language. Text that is not a key is returned unchanged, so worlds may also be written in a
+// single language, and is logged in case it is a key missing from every catalog.
+func (g *Game) message(key string) string {
+	if message, ok := g.messages[key]; ok {
+		return message
+	}
+	if g.base != nil {
+		if message, ok := g.base.Messages[key]; ok {
+			logger.Printf("%s: missing message %s, using %s", g.Language, key, defaultLanguage)
+			return message
+		}
+	}
+	if key != "" {
+		logger.Printf("%s: missing message %s", g.Language, key)
+	}
+	return key
+}
+
+// words returns the comma separated words in the messages for a list of keys,
+// such as the aliases of an item.
+func (g *Game) words(keys []string) []string {
+	var words []string
+	for _, key := range keys {
+		for _, word := range strings.Split(g.text(key), ",") {
+			if word = strings.TrimSpace(word); word != "" {
+				words = append(words, word)
+			}
+		}
+	}
+	return words
+}
+
+// eachText calls fn with the text of every name, description and string in the world,
+// which in a world kept apart from its language catalogs is the key of a message.
+func (g *Game) eachText(fn func(text string)) {
+	texts := func(t ...string) {
+		for _, s := range t {
+			if s != "" {
+				fn(s)
+			}
+		}
+	}
+	actions := func(do []action) {
+		for _, a := range do {
+			texts(a.Print, a.End)
+		}
+	}
+	verbs := func(v map[string]verbResponse) {
+		for _, response := range v {
+			texts(response.Text, response.BlockedString)
+		}
+	}
+	choices := func(c []choice) {
+		for _, ch := range c {
+			texts(ch.Text)
+			actions(ch.Do)
+		}
+	}
+	responses := func(r map[string][]response) {
+		for _, list := range r {
+			for _, response := range list {
+				texts(response.Text)
+				actions(response.Do)
+			}
+		}
+	}
+	addItem := func(i *item) {
+		texts(i.Name, i.Description, i.UnlockName, i.UnlockDescription, i.LockedString, i.UnlockString,
+			i.TakeableString, i.NotTakeableString, i.OpenString, i.CloseString, i.UseString, i.LockString,
+			i.BlockedString, i.RevealString, i.OnString, i.OffString)
+		texts(i.Aliases...)
+		texts(i.Adjectives...)
+		for _, s := range i.States {
+			texts(s.Name, s.Description, s.UseString)
+			for _, t := range s.Transitions {
+				texts(t.Text)
+			}
+		}
+		verbs(i.Verbs)
+	}
+
+	texts(g.Name, g.Description)
+	for _, r := range g.Rules {
+		actions(r.Do)
+	}
+	for _, e := range g.Events {
+		actions(e.Do)
+	}
+	walkItems(g.Player, addItem)
+	for r := range g.Rooms {
+		room := &g.Rooms[r]
+		texts(room.Name, room.Description, room.StoryString, room.BlockedString, room.DarkString, room.UnsafeString)
+		walkItems(room, addItem)
+		for _, e := range room.Exits {
+			texts(e.Name, e.Description, e.UnlockName, e.UnlockDescription, e.LockedString, e.UnlockString,
+				e.GoString, e.OpenString, e.CloseString, e.LockString, e.BlockedString, e.RevealString)
+			texts(e.Aliases...)
+			texts(e.Adjectives...)
+			verbs(e.Verbs)
+		}
+		for n := range room.NPCs {
+			npc := &room.NPCs[n]
+			texts(npc.Name, npc.Description, npc.ArriveString, npc.LeaveString, npc.Unknown, npc.Refuse)
+			texts(npc.Aliases...)
+			texts(npc.Adjectives...)
+			walkItems(npc, addItem)
+			choices(npc.Start)
+			for _, node := range npc.Dialogue {
+				texts(node.Text)
+				actions(node.Do)
+				choices(node.Choices)
+			}
+			responses(npc.Topics)
+			responses(npc.Gifts)
+		}
+	}
+}
+
+// Report compares the message catalog of every language with that of a base language, listing
+// the entries and messages each is missing, those the base language does not have, and
+// messages left the same as in the base language. Text in the world with no message in the
+// base language is listed as well.
+func Report(base string) (string, error) {
+	path := WorldFile + ".yaml"
+	yamlFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to find world file %s", path)
+	}
+	var g Game
+	err = yaml.Unmarshal(yamlFile, &g)
+	if err != nil {
+		return "", fmt.Errorf("Error parsing YAML file %s: %s", path, err)
+	}
+	b, err := readCatalog(base)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	add := func(format string, a ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, a...))
+	}
+
+	var untranslated []string
+	g.eachText(func(text string) {
+		if _, ok := b.Messages[text]; !ok && !containsID(untranslated, text) {
+			untranslated = append(untranslated, text)
+		}
+	})
+	sort.Strings(untranslated)
+	add("Language %s", base)
+	for _, text := range untranslated {
+		add("  no message: %q", text)
+	}
+
+	for _, lang := range ReadLanguages() {
+		if lang == base {
+			continue
+		}
+		c, err := readCatalog(lang)
+		if err != nil {
+			return "", err
+		}
+		add("Language %s", lang)
+		for _, section := range fixedSections {
+			for _, key := range missing(b.Dictionary[section], c.Dictionary[section]) {
+				add("  missing %s: %s", section, key)
+			}
+			for _, key := range missing(c.Dictionary[section], b.Dictionary[section]) {
+				add("  extra %s: %s", section, key)
+			}
+		}
+		for _, key := range missing(b.Messages, c.Messages) {
+			add("  missing message: %s", key)
+		}
+		for _, key := range missing(c.Messages, b.Messages) {
+			add("  extra message: %s", key)
+		}
+		var same []string
+		for key, message := range c.Messages {
+			if b.Messages[key] == message {
+				same = append(same, key)
+			}
+		}
+		sort.Strings(same)
+		for _, key := range same {
+			add("  untranslated message: %s", key)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// missing returns the sorted keys of a that are not in b.
+func missing(a map[string]string, b map[string]string) []string {
+	var keys []string
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
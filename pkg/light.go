@@ -0,0 +1,45 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+// isLit returns if the player can see in a room. Rooms are lit unless they are Dark, and
+// Dark rooms are lit by a lit light source in the room or in the player's inventory.
+func (g *Game) isLit(r *room) bool {
+	return !r.Dark || hasLight(r) || hasLight(g.Player)
+}
+
+// hasLight returns if a lit light source is visible in an itemContainer.
+// Light sources inside closed containers do not count.
+func hasLight(ic itemContainer) bool {
+	items := ic.getItems()
+	for index := range items {
+		item := &items[index]
+		if item.Hidden {
+			continue
+		}
+		if item.LightSource && item.Lit {
+			return true
+		}
+		if item.contentsVisible() && hasLight(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// darkString returns the text shown in place of a room's description while it is dark.
+func (g *Game) darkString(r *room) string {
+	if r.DarkString != "" {
+		return g.text(r.DarkString)
+	}
+	return g.str("dark")
+}
+
+// unsafeString returns the text shown when the player tries to enter an unsafe dark room
+// without a light source.
+func (g *Game) unsafeString(r *room) string {
+	if r.UnsafeString != "" {
+		return g.text(r.UnsafeString)
+	}
+	return g.format("errors", "tooDark", nil)
+}
@@ -0,0 +1,147 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"strings"
+)
+
+// directionPrefix marks the ids of directions in the name index, so they cannot clash
+// with the ids of items and exits.
+const directionPrefix = "direction:"
+
+// nameIndex maps the words a player may use for an object to the ids of the matching objects.
+type nameIndex struct {
+	nouns      map[string][]string
+	adjectives map[string][]string
+}
+
+// newNameIndex returns an empty nameIndex.
+func newNameIndex() *nameIndex {
+	return &nameIndex{
+		nouns:      make(map[string][]string),
+		adjectives: make(map[string][]string),
+	}
+}
+
+// normalise converts a phrase into the form used as a key in the name index.
+// Ignores case, apostrophes and repeated spaces.
+func normalise(phrase string) string {
+	phrase = strings.Replace(strings.ToLower(phrase), "'", "", -1)
+	return strings.Join(strings.Fields(phrase), " ")
+}
+
+// addNoun adds a name or alias for an object to the index.
+func (ix *nameIndex) addNoun(phrase string, id string) {
+	phrase = normalise(phrase)
+	if phrase == "" {
+		return
+	}
+	for _, existing := range ix.nouns[phrase] {
+		if existing == id {
+			return
+		}
+	}
+	ix.nouns[phrase] = append(ix.nouns[phrase], id)
+}
+
+// addAdjective adds a word that may be placed before the name or alias of an object.
+func (ix *nameIndex) addAdjective(word string, id string) {
+	ix.adjectives[id] = append(ix.adjectives[id], normalise(word))
+}
+
+// hasAdjective returns if an object may be described by a word.
+func (ix *nameIndex) hasAdjective(id string, word string) bool {
+	for _, adjective := range ix.adjectives[id] {
+		if adjective == word {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the ids of all objects a phrase may refer to.
+// A phrase is a name or alias, optionally preceded by any of the object's adjectives.
+// e.g "my bedside table" or "table".
+func (ix *nameIndex) resolve(phrase string) []string {
+	phrase = normalise(phrase)
+	if ids, ok := ix.nouns[phrase]; ok {
+		return ids
+	}
+	words := strings.Split(phrase, " ")
+	for k := 1; k < len(words); k++ {
+		var ids []string
+		for _, id := range ix.nouns[strings.Join(words[k:], " ")] {
+			described := true
+			for _, word := range words[:k] {
+				if !ix.hasAdjective(id, word) {
+					described = false
+					break
+				}
+			}
+			if described {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			return ids
+		}
+	}
+	return nil
+}
+
+// indexNames builds the name index for every item, exit, character and direction in the game,
+// in the language of the game. Must be called again whenever an object is renamed.
+func (g *Game) indexNames() {
+	ix := newNameIndex()
+	add := func(id string, name string, aliases []string, adjectives []string) {
+		ix.addNoun(g.text(name), id)
+		for _, alias := range g.words(aliases) {
+			ix.addNoun(alias, id)
+		}
+		for _, adjective := range g.words(adjectives) {
+			ix.addAdjective(adjective, id)
+		}
+	}
+	addItem := func(i *item) {
+		add(i.ID, i.Name, i.Aliases, i.Adjectives)
+	}
+	walkItems(g.Player, addItem)
+	for r := range g.Rooms {
+		walkItems(&g.Rooms[r], addItem)
+		for _, exit := range g.Rooms[r].Exits {
+			add(exit.ID, exit.Name, exit.Aliases, exit.Adjectives)
+		}
+		for n := range g.Rooms[r].NPCs {
+			npc := &g.Rooms[r].NPCs[n]
+			add(npc.ID, npc.Name, npc.Aliases, npc.Adjectives)
+			walkItems(npc, addItem)
+		}
+	}
+	for alias, id := range g.Dictionary["aliases"] {
+		ix.addNoun(alias, toID(id))
+	}
+	// Directions are indexed by the key used in the world file, their name and any shortcuts.
+	for key, direction := range g.Dictionary["directions"] {
+		ix.addNoun(key, directionPrefix+key)
+		ix.addNoun(direction, directionPrefix+key)
+	}
+	for shortcut, key := range g.Dictionary["directionShortcuts"] {
+		ix.addNoun(shortcut, directionPrefix+key)
+	}
+	g.names = ix
+}
+
+// stripArticles removes any leading articles listed in the Game Dictionary from a phrase.
+func (g *Game) stripArticles(phrase string) string {
+	words := strings.Fields(phrase)
+	for len(words) > 1 && g.Dictionary["articles"][strings.ToLower(words[0])] != "" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// resolve returns the ids of all objects a player entered phrase may refer to.
+func (g *Game) resolve(phrase string) []string {
+	return g.names.resolve(g.stripArticles(phrase))
+}
@@ -0,0 +1,75 @@
+package textgame
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testNames returns a game with a name index of two bedside tables, a guitar and a direction.
+func testNames() *Game {
+	ix := newNameIndex()
+	ix.addNoun("Jazminne's Bedside Table", "jazminnes-table")
+	ix.addNoun("bedside table", "jazminnes-table")
+	ix.addNoun("table", "jazminnes-table")
+	ix.addNoun("Table", "jazminnes-table")
+	ix.addAdjective("my", "jazminnes-table")
+	ix.addAdjective("Jazminne's", "jazminnes-table")
+	ix.addNoun("Liam's Bedside Table", "liams-table")
+	ix.addNoun("bedside table", "liams-table")
+	ix.addNoun("table", "liams-table")
+	ix.addAdjective("Liam's", "liams-table")
+	ix.addNoun("Guitar", "guitar")
+	ix.addAdjective("old", "guitar")
+	ix.addAdjective("acoustic", "guitar")
+	ix.addNoun("west", directionPrefix+"w")
+	ix.addNoun("w", directionPrefix+"w")
+	return &Game{names: ix, Dictionary: map[string]map[string]string{
+		"articles": {"the": "the", "a": "a"},
+	}}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		phrase string
+		want   []string
+	}{
+		// Names and aliases, ignoring case, apostrophes and spaces.
+		{"guitar", []string{"guitar"}},
+		{"  GUITAR ", []string{"guitar"}},
+		{"jazminne's bedside table", []string{"jazminnes-table"}},
+		{"Jazminnes  Bedside Table", []string{"jazminnes-table"}},
+		{"w", []string{directionPrefix + "w"}},
+
+		// Aliases shared by several objects are ambiguous.
+		{"table", []string{"jazminnes-table", "liams-table"}},
+		{"bedside table", []string{"jazminnes-table", "liams-table"}},
+
+		// Adjectives pick out the objects they describe.
+		{"my table", []string{"jazminnes-table"}},
+		{"my bedside table", []string{"jazminnes-table"}},
+		{"liam's table", []string{"liams-table"}},
+		{"old guitar", []string{"guitar"}},
+		{"old acoustic guitar", []string{"guitar"}},
+
+		// Articles are left off.
+		{"the guitar", []string{"guitar"}},
+		{"a the old guitar", []string{"guitar"}},
+
+		// Every word must be part of a name or describe the object.
+		{"new guitar", nil},
+		{"my liams table", nil},
+		{"old my table", nil},
+		{"guitar old", nil},
+		{"bedside", nil},
+		{"jazminnes", nil},
+		{"tab", nil},
+		{"the", nil},
+		{"", nil},
+	}
+	g := testNames()
+	for _, test := range tests {
+		if got := g.resolve(test.phrase); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("resolve(%q) = %v, want %v", test.phrase, got, test.want)
+		}
+	}
+}
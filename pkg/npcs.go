@@ -0,0 +1,449 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// npc is a character the player can talk to, ask about things and give items to.
+// Conversations begin at the dialogue node named "start", unless one of the Start
+// choices has its conditions met.
+// Characters may follow the player, keep to a Schedule, or walk a Patrol route of room IDs,
+// taking a step every Every turns.
+type npc struct {
+	ID          string
+	Name        string
+	Description string
+	Aliases     []string
+	Adjectives  []string
+	Items       []item
+
+	Follow       bool
+	Schedule     []stop
+	Patrol       []int
+	Every        int
+	Step         int
+	ArriveString string
+	LeaveString  string
+
+	Start    []choice
+	Dialogue map[string]dialogueNode
+	Topics   map[string][]response
+	Gifts    map[string][]response
+	Unknown  string
+	Refuse   string
+}
+
+// dialogueNode is one step of a conversation, with the choices the player may reply with.
+// A node with no choices available ends the conversation.
+type dialogueNode struct {
+	Text    string
+	Do      []action
+	Choices []choice
+}
+
+// choice is a reply the player can choose in a conversation, leading to the dialogue node
+// named in Goto. Choices with no Goto end the conversation.
+type choice struct {
+	Text string
+	When []condition
+	Do   []action
+	Goto string
+}
+
+// response is how a character answers a question or reacts to a gift. Characters keep
+// the items they are given unless Return is set.
+type response struct {
+	Text   string
+	When   []condition
+	Do     []action
+	Return bool
+}
+
+// stop is a place in a character's schedule. From turn Turn the character is in room Room.
+type stop struct {
+	Turn int
+	Room int
+}
+
+// conversation records the character the player is talking to and the current dialogue node.
+type conversation struct {
+	NPC  string
+	Node string
+}
+
+// startNode is the dialogue node a conversation begins at if no Start choice applies.
+const startNode = "start"
+
+// getItems returns a slice of items held by a character.
+func (n *npc) getItems() []item {
+	return n.Items
+}
+
+// setItems sets the items held by a character.
+func (n *npc) setItems(items []item) {
+	n.Items = items
+}
+
+// getNPCByName returns a character in the current room matching a provided name or alias.
+// Characters in a dark room cannot be seen.
+func (g *Game) getNPCByName(name string) *npc {
+	if !g.isLit(g.CurrentRoom) {
+		return nil
+	}
+	for _, id := range g.resolve(name) {
+		if n := g.CurrentRoom.getNPCByID(id); n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// getNPCByID returns the character with an id in a room.
+func (r *room) getNPCByID(id string) *npc {
+	for index := range r.NPCs {
+		if r.NPCs[index].ID == id {
+			return &r.NPCs[index]
+		}
+	}
+	return nil
+}
+
+// npcByID returns the character with an id in any room, and the room they are in.
+func (g *Game) npcByID(id string) (*npc, *room) {
+	for r := range g.Rooms {
+		if n := g.Rooms[r].getNPCByID(id); n != nil {
+			return n, &g.Rooms[r]
+		}
+	}
+	return nil, nil
+}
+
+// npcHere returns if the character with an id is visible to the player.
+func (g *Game) npcHere(id string) bool {
+	return g.isLit(g.CurrentRoom) && g.CurrentRoom.getNPCByID(id) != nil
+}
+
+// isNPC returns if there is a character with an id in any room.
+func (g *Game) isNPC(id string) bool {
+	n, _ := g.npcByID(id)
+	return n != nil
+}
+
+// getNPCOptions returns a formatted string of all characters in a room.
+func (g *Game) getNPCOptions(r *room) string {
+	var options string
+	for _, n := range r.NPCs {
+		options += " [" + render("characters", g.text(n.Name)) + "]"
+	}
+	return options
+}
+
+// moveNPC moves the character with an id to a room. With no room the character is
+// moved to the current room. The source names what asked for the move in errors.
+func (g *Game) moveNPC(id string, roomID int, source string) error {
+	n, from := g.npcByID(id)
+	if n == nil {
+		return fmt.Errorf("%s refers to unknown character %s", source, id)
+	}
+	to := g.CurrentRoom
+	if roomID != 0 {
+		to = g.getRoomByID(roomID)
+		if to == nil {
+			return fmt.Errorf("%s refers to unknown room %d", source, roomID)
+		}
+	}
+	if from == to {
+		return nil
+	}
+	moved := *n
+	for index := range from.NPCs {
+		if from.NPCs[index].ID == id {
+			from.NPCs = append(from.NPCs[:index], from.NPCs[index+1:]...)
+			break
+		}
+	}
+	to.NPCs = append(to.NPCs, moved)
+	if from == g.CurrentRoom || to == g.CurrentRoom {
+		g.DisplayItemInfo = true
+	}
+	return nil
+}
+
+// moveNPCs moves every character that follows the player, keeps to a schedule or walks a patrol.
+// Characters moving into or out of the player's room are announced.
+func (g *Game) moveNPCs() error {
+	type move struct {
+		id   string
+		room int
+	}
+	var moves []move
+	for r := range g.Rooms {
+		for index := range g.Rooms[r].NPCs {
+			n := &g.Rooms[r].NPCs[index]
+			to := g.destination(n)
+			if to != 0 && to != g.Rooms[r].ID {
+				moves = append(moves, move{n.ID, to})
+			}
+		}
+	}
+	for _, m := range moves {
+		err := g.walkNPC(m.id, m.room)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// destination returns the room a character should move to this turn, or 0 if it stays put.
+// Following the player takes priority over a schedule, and a schedule over a patrol.
+func (g *Game) destination(n *npc) int {
+	if n.Follow {
+		return g.CurrentRoomID
+	}
+	if len(n.Schedule) > 0 {
+		room := 0
+		for _, s := range n.Schedule {
+			if s.Turn <= g.Turn {
+				room = s.Room
+			}
+		}
+		return room
+	}
+	if len(n.Patrol) > 0 && (n.Every <= 1 || g.Turn%n.Every == 0) {
+		n.Step = (n.Step + 1) % len(n.Patrol)
+		return n.Patrol[n.Step]
+	}
+	return 0
+}
+
+// walkNPC moves a character to a room, announcing them leaving or arriving in the player's room.
+func (g *Game) walkNPC(id string, roomID int) error {
+	n, from := g.npcByID(id)
+	if n == nil {
+		return errors.New("Unknown character " + id)
+	}
+	name, arrive, leave := g.named(n.ID, n.Name), g.text(n.ArriveString), g.text(n.LeaveString)
+	err := g.moveNPC(id, roomID, "Schedule of "+n.ID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case from == g.CurrentRoom && leave != "":
+		fmt.Fprintln(screen, render("text", leave))
+	case from == g.CurrentRoom:
+		fmt.Fprint(screen, render("text", g.str("npcLeaves", vars{"character": name})))
+		fmt.Fprintln(screen)
+	case roomID == g.CurrentRoomID && arrive != "":
+		fmt.Fprintln(screen, render("text", arrive))
+	case roomID == g.CurrentRoomID:
+		fmt.Fprint(screen, render("text", g.str("npcArrives", vars{"character": name})))
+		fmt.Fprintln(screen)
+	}
+	return nil
+}
+
+// setFollow starts or stops the character with an id following the player. The source names
+// what asked for it in errors.
+func (g *Game) setFollow(id string, follow bool, source string) error {
+	n, _ := g.npcByID(id)
+	if n == nil {
+		return fmt.Errorf("%s refers to unknown character %s", source, id)
+	}
+	n.Follow = follow
+	return nil
+}
+
+// checkNPCs validates the characters in the yaml configuration and returns a description of
+// each problem found.
+func (g *Game) checkNPCs() []string {
+	var problems []string
+	for _, room := range g.Rooms {
+		for _, n := range room.NPCs {
+			for _, s := range n.Schedule {
+				if g.getRoomByID(s.Room) == nil {
+					problems = append(problems, fmt.Sprintf("Schedule of %s refers to unknown room %d", n.ID, s.Room))
+				}
+			}
+			for _, id := range n.Patrol {
+				if g.getRoomByID(id) == nil {
+					problems = append(problems, fmt.Sprintf("Patrol of %s refers to unknown room %d", n.ID, id))
+				}
+			}
+			gotos := n.Start
+			for _, node := range n.Dialogue {
+				gotos = append(gotos, node.Choices...)
+			}
+			for _, c := range gotos {
+				if _, ok := n.Dialogue[c.Goto]; c.Goto != "" && !ok {
+					problems = append(problems, fmt.Sprintf("Dialogue of %s refers to unknown node %s", n.ID, c.Goto))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// talk begins a conversation with a character in the room.
+func (g *Game) talk(name string) error {
+	n := g.getNPCByName(name)
+	if n == nil {
+		return g.errorf("noCharacter", vars{"name": name})
+	}
+	node := startNode
+	for _, c := range n.Start {
+		if g.met(c.When) {
+			node = c.Goto
+			break
+		}
+	}
+	if _, ok := n.Dialogue[node]; !ok {
+		return g.errorf("nothingToSay", vars{"character": g.named(n.ID, n.Name)})
+	}
+	return g.enterNode(n, node)
+}
+
+// enterNode shows a dialogue node and the choices the player may reply with.
+// The conversation ends if there are no choices.
+func (g *Game) enterNode(n *npc, name string) error {
+	node, ok := n.Dialogue[name]
+	if !ok {
+		g.Conversation = conversation{}
+		return fmt.Errorf("Dialogue of %s refers to unknown node %s", n.ID, name)
+	}
+	fmt.Fprintln(screen, render("text", g.text(node.Text)))
+	err := g.do(node.Do, "Dialogue of "+n.ID)
+	if err != nil {
+		return err
+	}
+	choices := g.choices(node)
+	if len(choices) == 0 {
+		g.Conversation = conversation{}
+		return nil
+	}
+	g.Conversation = conversation{NPC: n.ID, Node: name}
+	fmt.Fprintln(screen)
+	for index, c := range choices {
+		fmt.Fprintf(screen, "%d. %s\n", index+1, render("text", g.text(c.Text)))
+	}
+	return nil
+}
+
+// choices returns the choices in a dialogue node whose conditions are met.
+func (g *Game) choices(node dialogueNode) []choice {
+	var available []choice
+	for _, c := range node.Choices {
+		if g.met(c.When) {
+			available = append(available, c)
+		}
+	}
+	return available
+}
+
+// inConversation returns if the player is talking to a character who is still in the room.
+func (g *Game) inConversation() bool {
+	return g.Conversation.NPC != "" && g.CurrentRoom.getNPCByID(g.Conversation.NPC) != nil
+}
+
+// choose replies to the character the player is talking to with a numbered choice.
+func (g *Game) choose(input string) error {
+	n := g.CurrentRoom.getNPCByID(g.Conversation.NPC)
+	choices := g.choices(n.Dialogue[g.Conversation.Node])
+	number, err := strconv.Atoi(input)
+	if err != nil || number < 1 || number > len(choices) {
+		return g.errorf("invalidChoice", vars{"input": input})
+	}
+	c := choices[number-1]
+	err = g.do(c.Do, "Dialogue of "+n.ID)
+	if err != nil {
+		return err
+	}
+	if c.Goto == "" {
+		g.Conversation = conversation{}
+		return nil
+	}
+	return g.enterNode(n, c.Goto)
+}
+
+// respond prints the first response whose conditions are met and carries out its actions.
+// Returns nil if no response applies.
+func (g *Game) respond(responses []response) (*response, error) {
+	for index := range responses {
+		r := &responses[index]
+		if g.met(r.When) {
+			fmt.Fprintln(screen, render("text", g.text(r.Text)))
+			return r, g.do(r.Do, "Response")
+		}
+	}
+	return nil, nil
+}
+
+// ask asks a character in the room about an item, exit, character or topic.
+func (g *Game) ask(name string, topic string) error {
+	n := g.getNPCByName(name)
+	if n == nil {
+		return g.errorf("noCharacter", vars{"name": name})
+	}
+	if topic == "" {
+		return g.errorf("askAbout", vars{"character": g.named(n.ID, n.Name)})
+	}
+	ids := g.resolve(topic)
+	for key, responses := range n.Topics {
+		if toID(key) != toID(g.stripArticles(topic)) && !containsID(ids, toID(key)) {
+			continue
+		}
+		r, err := g.respond(responses)
+		if r != nil || err != nil {
+			return err
+		}
+	}
+	if n.Unknown != "" {
+		return errors.New(g.text(n.Unknown))
+	}
+	return g.errorf("noAnswer", vars{"character": g.named(n.ID, n.Name)})
+}
+
+// give gives an item from the player's inventory to a character in the room.
+func (g *Game) give(name string, to string) error {
+	item := g.findItem(name, g.Player)
+	if item == nil {
+		return g.errorf("notInInventory", vars{"name": name})
+	}
+	if to == "" {
+		return g.errorf("giveTo", vars{"item": g.named(item.ID, item.Name)})
+	}
+	n := g.getNPCByName(to)
+	if n == nil {
+		return g.errorf("noCharacter", vars{"name": to})
+	}
+	id, npcID := item.ID, n.ID
+	for key, responses := range n.Gifts {
+		if !item.is(key) {
+			continue
+		}
+		r, err := g.respond(responses)
+		if r == nil && err == nil {
+			continue
+		}
+		if r != nil && !r.Return {
+			// Actions may have moved the item or character, so both are looked up again.
+			if given := detachFrom(id, g.Player); given != nil {
+				if n, _ = g.npcByID(npcID); n != nil {
+					push(*given, n)
+				} else {
+					push(*given, g.Player)
+				}
+				g.DisplayItemInfo = true
+			}
+		}
+		return err
+	}
+	if n.Refuse != "" {
+		return errors.New(g.text(n.Refuse))
+	}
+	return g.errorf("refused", vars{"character": g.named(n.ID, n.Name), "item": g.named(item.ID, item.Name)})
+}
@@ -0,0 +1,196 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// output writes the game's text to the player's screen, wrapped to its width without breaking
+// words, and paged when more lines are written than fit on the screen before the player types.
+// A transcript of the game is kept as it was written, unwrapped and without styles.
+type output struct {
+	w          io.Writer
+	width      int
+	paged      bool
+	clearMode  string
+	transcript io.Writer
+	line       []byte
+	lines      int
+	more       string
+}
+
+// The ways the screen may be cleared when the player enters a room.
+const (
+	// ClearANSI clears a terminal with ANSI escape codes, and does nothing when output is not a terminal.
+	ClearANSI = "ansi"
+	// ClearOff never clears the screen.
+	ClearOff = "off"
+	// ClearScrollback keeps what was written and draws a line under it instead.
+	ClearScrollback = "scrollback"
+)
+
+// screen is the output all of the game's text is written to.
+var screen = &output{w: os.Stdout, paged: true, clearMode: ClearANSI, more: "[more]"}
+
+// input reads the lines typed by the player, both while playing and when creating the player.
+var input = bufio.NewReader(os.Stdin)
+
+// separatorWidth is the width of the line drawn between rooms in scrollback mode when the
+// width of the screen is unknown.
+const separatorWidth = 40
+
+// escapeCodes match the ANSI escape codes in text, which take no space on the screen.
+var escapeCodes = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// SetWidth wraps the game's text to a number of columns. With a width of 0 text is wrapped to
+// the width of the terminal, and is not wrapped when the output is not a terminal.
+func SetWidth(width int) {
+	screen.width = width
+}
+
+// SetClear sets how the screen is cleared when the player enters a room: ClearANSI,
+// ClearOff or ClearScrollback.
+func SetClear(mode string) error {
+	switch mode {
+	case ClearANSI, ClearOff, ClearScrollback:
+		screen.clearMode = mode
+		return nil
+	}
+	return fmt.Errorf("Unknown clear mode %s, use %s, %s or %s", mode, ClearANSI, ClearOff, ClearScrollback)
+}
+
+// SetTranscript keeps a transcript of the game, with the commands typed by the player, in w.
+func SetTranscript(w io.Writer) {
+	screen.transcript = w
+}
+
+// readLine reads a line typed by the player, without surrounding spaces.
+// Returns io.EOF once there is nothing more to read, such as at the end of piped input.
+func readLine() (string, error) {
+	screen.flush()
+	line, err := input.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	line = strings.TrimSpace(line)
+	if screen.transcript != nil && err == nil {
+		fmt.Fprintln(screen.transcript, line)
+	}
+	screen.lines = 0
+	return line, err
+}
+
+// Write writes text to the screen and transcript. Lines are written to the screen once they
+// are complete, so they can be wrapped.
+func (o *output) Write(p []byte) (int, error) {
+	if o.transcript != nil {
+		o.transcript.Write([]byte(escapeCodes.ReplaceAllString(string(p), "")))
+	}
+	o.line = append(o.line, p...)
+	for {
+		end := bytes.IndexByte(o.line, '\n')
+		if end < 0 {
+			break
+		}
+		o.writeLine(string(o.line[:end]))
+		o.line = o.line[end+1:]
+	}
+	return len(p), nil
+}
+
+// flush writes an incomplete line to the screen, such as a prompt.
+func (o *output) flush() {
+	o.w.Write(o.line)
+	o.line = nil
+}
+
+// clear clears the screen, or separates what has been written from what follows, without
+// clearing the transcript.
+func (o *output) clear() {
+	o.flush()
+	switch o.clearMode {
+	case ClearANSI:
+		if isTerminal(os.Stdout) {
+			fmt.Fprint(o.w, "\x1b[H\x1b[2J")
+			o.lines = 0
+		}
+	case ClearScrollback:
+		width, _ := o.size()
+		if width <= 0 {
+			width = separatorWidth
+		}
+		fmt.Fprintln(o, strings.Repeat("-", width))
+	}
+}
+
+// writeLine writes a line to the screen, wrapped to its width, pausing for the player to read
+// what has been written when the screen is full.
+func (o *output) writeLine(line string) {
+	width, height := o.size()
+	for _, l := range strings.Split(wrap(line, width), "\n") {
+		if o.paged && height > 1 && o.lines >= height-1 && isTerminal(os.Stdin) {
+			o.page()
+		}
+		fmt.Fprintln(o.w, l)
+		o.lines++
+	}
+}
+
+// page asks the player to press enter to see more text, then clears the prompt.
+func (o *output) page() {
+	fmt.Fprint(o.w, o.more)
+	input.ReadString('\n')
+	fmt.Fprint(o.w, "\x1b[1A\x1b[2K")
+	o.lines = 0
+}
+
+// size returns the number of columns text is wrapped to and the number of lines on the screen.
+// Either is 0 when the screen is not a terminal.
+func (o *output) size() (int, int) {
+	if !isTerminal(os.Stdout) {
+		return o.width, 0
+	}
+	width, height := terminalSize()
+	if o.width > 0 {
+		width = o.width
+	}
+	return width, height
+}
+
+// wrap breaks a line into lines no wider than a number of columns at its spaces. Words wider
+// than a line are left whole.
+func wrap(line string, width int) string {
+	if width <= 0 || visibleLength(line) <= width {
+		return line
+	}
+	var b strings.Builder
+	column := 0
+	for i, word := range strings.Split(line, " ") {
+		length := visibleLength(word)
+		if i > 0 {
+			if column > 0 && column+1+length > width {
+				b.WriteByte('\n')
+				column = 0
+			} else {
+				b.WriteByte(' ')
+				column++
+			}
+		}
+		b.WriteString(word)
+		column += length
+	}
+	return b.String()
+}
+
+// visibleLength returns the number of columns text takes on the screen.
+func visibleLength(text string) int {
+	return utf8.RuneCountInString(escapeCodes.ReplaceAllString(text, ""))
+}
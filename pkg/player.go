@@ -0,0 +1,109 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// playerID is the ID of the player when named in a template, so the player has a grammar of
+// their own, given by their pronouns.
+const playerID = "player"
+
+// pronounSet is a set of pronouns the player may choose, such as she/her, kept by a key that is
+// the same in every language. Name is how the set is shown and typed by the player.
+// Gender and Plural are how the words describing the player agree with them.
+type pronounSet struct {
+	Name       string
+	Gender     string
+	Plural     bool
+	Subject    string
+	Object     string
+	Possessive string
+	Reflexive  string
+}
+
+// pronounSet returns a set of pronouns from the catalog, falling back to the default language.
+func (g *Game) pronounSet(key string) (pronounSet, bool) {
+	if p, ok := g.grammar.Pronouns[key]; ok {
+		return p, true
+	}
+	if g.base != nil {
+		if p, ok := g.base.Grammar.Pronouns[key]; ok {
+			logger.Printf("%s: missing pronouns %s, using %s", g.Language, key, defaultLanguage)
+			return p, true
+		}
+	}
+	return pronounSet{}, false
+}
+
+// pronounKey returns the key of the set of pronouns the player typed, which may be its key,
+// its name or its subject pronoun.
+func (g *Game) pronounKey(typed string) (string, bool) {
+	for key, p := range g.grammar.Pronouns {
+		for _, s := range []string{key, p.Name, p.Subject} {
+			if strings.EqualFold(typed, s) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// pronounNames returns the names of the sets of pronouns in the catalog, in order.
+func (g *Game) pronounNames() []string {
+	var names []string
+	for _, p := range g.grammar.Pronouns {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetPlayer sets the name and pronouns of the player. An empty name or pronouns keeps those
+// the game already has.
+func (g *Game) SetPlayer(name string, pronouns string) error {
+	if pronouns != "" {
+		key, ok := g.pronounKey(pronouns)
+		if !ok {
+			return g.errorf("unknownPronouns", vars{"input": pronouns, "pronouns": strings.Join(g.pronounNames(), ", ")})
+		}
+		g.Player.Pronouns = key
+	}
+	if name != "" {
+		g.Player.Name = name
+	}
+	return nil
+}
+
+// CreatePlayer asks the player for the name and pronouns they would like to play with,
+// unless they were already given. Nothing typed keeps those of the world.
+func (g *Game) CreatePlayer(name string, pronouns string) error {
+	if name != "" && pronouns != "" {
+		return g.SetPlayer(name, pronouns)
+	}
+	if name == "" {
+		fmt.Fprint(screen, render("prompt", g.str("askName")))
+		name, _ = readLine()
+	}
+	for pronouns == "" {
+		current := ""
+		if p, ok := g.pronounSet(g.Player.Pronouns); ok {
+			current = p.Name
+		}
+		fmt.Fprint(screen, render("prompt", g.str("askPronouns", vars{"pronouns": strings.Join(g.pronounNames(), ", "), "current": current})))
+		typed, _ := readLine()
+		if typed == "" {
+			break
+		}
+		if _, ok := g.pronounKey(typed); !ok {
+			fmt.Fprint(screen, render("error", g.errorf("unknownPronouns", vars{"input": typed, "pronouns": strings.Join(g.pronounNames(), ", ")}).Error()))
+			continue
+		}
+		pronouns = typed
+	}
+	fmt.Fprintln(screen)
+	return g.SetPlayer(name, pronouns)
+}
@@ -0,0 +1,129 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// ThemeFile is the theme styling the game's output, unless another is chosen.
+const ThemeFile = ConfDir + "theme.yaml"
+
+// reset is the ANSI escape code ending a style.
+const reset = "\x1b[0m"
+
+// styles hold the ANSI escape codes of each style of the theme, such as "room" or "error".
+// Output is plain text while there are none.
+var styles = map[string]string{}
+
+// attributes are the ANSI codes of the words a style is written with in a theme.
+// Colours may also be bright, as in "bright-red", or a background, as in "on-blue".
+var attributes = map[string]int{
+	"bold": 1, "dim": 2, "italic": 3, "underline": 4, "reverse": 7,
+	"black": 30, "red": 31, "green": 32, "yellow": 33, "blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+}
+
+// LoadTheme reads a theme file, styling the game's output with ANSI colours and bold text.
+// Output is left plain when the NO_COLOR environment variable is set, or is not a terminal.
+func LoadTheme(path string) error {
+	styles = map[string]string{}
+	if os.Getenv("NO_COLOR") != "" || !isTerminal(os.Stdout) {
+		return nil
+	}
+	yamlFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to find theme file %s", path)
+	}
+	var theme map[string]string
+	err = yaml.Unmarshal(yamlFile, &theme)
+	if err != nil {
+		return fmt.Errorf("Error parsing YAML file %s: %s", path, err)
+	}
+	for name, style := range theme {
+		code, err := escapeCode(style)
+		if err != nil {
+			return fmt.Errorf("Error in theme file %s: %s", path, err)
+		}
+		styles[name] = code
+	}
+	return nil
+}
+
+// isTerminal returns if a file is a terminal rather than a pipe or a regular file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// escapeCode returns the ANSI escape code of a style written as words, such as "bold yellow".
+func escapeCode(style string) (string, error) {
+	var codes []string
+	for _, word := range strings.Fields(style) {
+		offset := 0
+		switch {
+		case strings.HasPrefix(word, "bright-"):
+			word, offset = strings.TrimPrefix(word, "bright-"), 60
+		case strings.HasPrefix(word, "on-"):
+			word, offset = strings.TrimPrefix(word, "on-"), 10
+		}
+		code, ok := attributes[word]
+		if !ok || (offset != 0 && code < 30) {
+			return "", fmt.Errorf("unknown style %s", style)
+		}
+		codes = append(codes, fmt.Sprint(code+offset))
+	}
+	if len(codes) == 0 {
+		return "", nil
+	}
+	return "\x1b[" + strings.Join(codes, ";") + "m", nil
+}
+
+// markupMarks escapes the marks of markup in text, so it is rendered as it is written.
+var markupMarks = strings.NewReplacer("*", "**", "[", "[[", "]", "]]")
+
+// render returns text in a style of the theme. Text may also mark up *emphasis* and
+// [objects], written without the marks when output is plain. Write ** [[ or ]] for the mark itself.
+func render(style string, text string) string {
+	code := styles[style]
+	body := strings.TrimRight(text, "\n")
+	var b strings.Builder
+	b.WriteString(code)
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if (c == '*' || c == '[' || c == ']') && i+1 < len(body) && body[i+1] == c {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		closing, markup := byte(0), ""
+		switch c {
+		case '*':
+			closing, markup = '*', "emphasis"
+		case '[':
+			closing, markup = ']', "object"
+		}
+		end := -1
+		if closing != 0 {
+			end = strings.IndexByte(body[i+1:], closing)
+		}
+		if end <= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		span := body[i+1 : i+1+end]
+		if len(styles) > 0 {
+			span = styles[markup] + span + reset + code
+		}
+		b.WriteString(span)
+		i += end + 1
+	}
+	if code != "" {
+		b.WriteString(reset)
+	}
+	b.WriteString(text[len(body):])
+	return b.String()
+}
@@ -0,0 +1,302 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+)
+
+// rule reacts to a player command, declared in the yaml configuration.
+// When a command matches On, Object and Target, and every condition in When is met,
+// the actions in Do are carried out in place of the command.
+type rule struct {
+	On     string
+	Object string
+	Target string
+	When   []condition
+	Do     []action
+	Once   bool
+	Fired  bool
+}
+
+// action is a single step carried out by a rule. Only the fields that are set are acted on.
+type action struct {
+	Print    string
+	Var      string
+	Set      interface{}
+	Add      int
+	Move     string
+	To       string
+	ToRoom   int
+	Reveal   string
+	Unlock   string
+	Open     string
+	Takeable string
+	Go       string
+	Teleport int
+	End      string
+	MoveNPC  string
+	Follow   string
+	Stay     string
+	SetState string
+	ToState  string
+}
+
+// compileRules converts the UnlockedWith and TakeableWith shorthand on items and exits
+// into rules, so that using an item on another is handled in one place.
+func (g *Game) compileRules() {
+	var rules []rule
+	useOn := func(ref string, target string, check condition, do ...action) {
+		rules = append(rules, rule{
+			On:     "use",
+			Object: toID(ref),
+			Target: target,
+			When:   []condition{check},
+			Do:     do,
+		})
+	}
+	addItem := func(i *item) {
+		if i.TakeableWith != "" {
+			useOn(i.TakeableWith, i.ID, condition{Takeable: i.ID, Not: true},
+				action{Print: i.TakeableString, Takeable: i.ID},
+				action{Move: i.ID, To: inventory})
+		}
+		if i.UnlockedWith != "" {
+			useOn(i.UnlockedWith, i.ID, condition{Locked: i.ID},
+				action{Unlock: i.ID},
+				action{Open: i.ID})
+		}
+	}
+	walkItems(g.Player, addItem)
+	for r := range g.Rooms {
+		walkItems(&g.Rooms[r], addItem)
+		for n := range g.Rooms[r].NPCs {
+			walkItems(&g.Rooms[r].NPCs[n], addItem)
+		}
+		for _, exit := range g.Rooms[r].Exits {
+			if exit.UnlockedWith != "" {
+				useOn(exit.UnlockedWith, exit.ID, condition{Locked: exit.ID},
+					action{Unlock: exit.ID},
+					action{Go: exit.ID})
+			}
+		}
+	}
+	g.shorthand = rules
+}
+
+// visibleIDs returns the ids of all items, exits and characters visible to the player that a
+// phrase may refer to. Directions refer to the exit in the current room in that direction.
+func (g *Game) visibleIDs(phrase string) []string {
+	exit := g.CurrentRoom.getExitByDirection(g.expandDirection(phrase))
+	if exit != nil {
+		return []string{exit.ID}
+	}
+	var ids []string
+	for _, id := range g.resolve(phrase) {
+		if g.getItemByID(id) != nil || g.CurrentRoom.getExitByID(id) != nil || g.npcHere(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// matches returns if a rule applies to a command and its objects.
+func (g *Game) matches(r *rule, command string, object string, target string) bool {
+	if r.On != command || (r.Once && r.Fired) {
+		return false
+	}
+	if r.Object != "" && !containsID(g.visibleIDs(object), toID(r.Object)) {
+		return false
+	}
+	if r.Target != "" && !containsID(g.visibleIDs(target), toID(r.Target)) {
+		return false
+	}
+	return g.met(r.When)
+}
+
+// containsID is a helper function to return if an id appears in a slice.
+func containsID(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// runRules carries out the first rule matching a command. Rules from the yaml configuration
+// are checked before the shorthand rules. Returns if a rule was found.
+func (g *Game) runRules(command string, object string, target string) (bool, error) {
+	for _, rules := range [][]rule{g.Rules, g.shorthand} {
+		for index := range rules {
+			r := &rules[index]
+			if g.matches(r, command, object, target) {
+				r.Fired = true
+				return true, g.do(r.Do, "Rule")
+			}
+		}
+	}
+	return false, nil
+}
+
+// do carries out a list of actions, stopping at the first that fails. The source, such as
+// "Rule", names what the actions belong to in errors.
+func (g *Game) do(actions []action, source string) error {
+	for _, a := range actions {
+		err := g.doAction(a, source)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// doAction carries out each part of an action that is set. The source names what the action
+// belongs to in errors.
+func (g *Game) doAction(a action, source string) error {
+	if a.Print != "" {
+		fmt.Fprintln(screen, render("text", g.text(a.Print)))
+	}
+	if a.Var != "" {
+		g.apply([]effect{{Var: a.Var, Set: a.Set, Add: a.Add}})
+	}
+	if a.Takeable != "" {
+		item := g.itemByID(toID(a.Takeable))
+		if item == nil {
+			return fmt.Errorf("%s refers to unknown item %s", source, a.Takeable)
+		}
+		item.Takeable = true
+	}
+	if a.Reveal != "" {
+		if !g.reveal(toID(a.Reveal)) {
+			return fmt.Errorf("%s refers to unknown object %s", source, a.Reveal)
+		}
+	}
+	if a.Unlock != "" {
+		id := toID(a.Unlock)
+		if item := g.itemByID(id); item != nil {
+			g.unlockItem(item)
+		} else if exit := g.exitByID(id); exit != nil {
+			g.unlockExit(exit)
+		} else {
+			return fmt.Errorf("%s refers to unknown object %s", source, a.Unlock)
+		}
+	}
+	if a.Open != "" {
+		item := g.itemByID(toID(a.Open))
+		if item != nil && item.Openable && !item.Open && !item.Locked {
+			item.Open = true
+			g.DisplayItemInfo = true
+			fmt.Fprintln(screen, render("text", g.text(item.OpenString)))
+		}
+	}
+	if a.Move != "" {
+		err := g.move(toID(a.Move), a.To, a.ToRoom, source)
+		if err != nil {
+			return err
+		}
+	}
+	if a.MoveNPC != "" {
+		err := g.moveNPC(toID(a.MoveNPC), a.ToRoom, source)
+		if err != nil {
+			return err
+		}
+	}
+	if a.SetState != "" {
+		item := g.itemByID(toID(a.SetState))
+		if item == nil {
+			return fmt.Errorf("%s refers to unknown item %s", source, a.SetState)
+		}
+		err := g.setState(item, a.ToState)
+		if err != nil {
+			return err
+		}
+	}
+	if a.Follow != "" {
+		err := g.setFollow(toID(a.Follow), true, source)
+		if err != nil {
+			return err
+		}
+	}
+	if a.Stay != "" {
+		err := g.setFollow(toID(a.Stay), false, source)
+		if err != nil {
+			return err
+		}
+	}
+	if a.Go != "" {
+		exit := g.CurrentRoom.getExitByID(toID(a.Go))
+		if exit == nil {
+			return fmt.Errorf("%s refers to unknown exit %s", source, a.Go)
+		}
+		err := g.goDirection(exit.Direction)
+		if err != nil {
+			return err
+		}
+	}
+	if a.Teleport != 0 {
+		room := g.getRoomByID(a.Teleport)
+		if room == nil {
+			return fmt.Errorf("%s refers to unknown room %d", source, a.Teleport)
+		}
+		g.setCurrentRoom(room)
+	}
+	if a.End != "" {
+		fmt.Fprintln(screen, render("text", g.text(a.End)))
+		g.Over = true
+	}
+	return nil
+}
+
+// inventory is the destination used by rules to move an item into the player's inventory.
+const inventory = "inventory"
+
+// move detaches an item from wherever it is in the game and places it in the player's
+// inventory, inside another item, or in a room. With no destination the item is placed
+// in the current room. The source names what asked for the move in errors.
+func (g *Game) move(id string, to string, toRoom int, source string) error {
+	if to != "" && to != inventory && g.itemByID(toID(to)) == nil {
+		return fmt.Errorf("%s refers to unknown item %s", source, to)
+	}
+	if toRoom != 0 && g.getRoomByID(toRoom) == nil {
+		return fmt.Errorf("%s refers to unknown room %d", source, toRoom)
+	}
+	moved := g.detach(id)
+	if moved == nil {
+		return fmt.Errorf("%s refers to unknown item %s", source, id)
+	}
+	// Containers are looked up after detaching, as the item's removal may move them in memory.
+	var destination itemContainer = g.CurrentRoom
+	switch {
+	case to == inventory:
+		destination = g.Player
+	case to != "":
+		destination = g.itemByID(toID(to))
+	case toRoom != 0:
+		destination = g.getRoomByID(toRoom)
+	}
+	push(*moved, destination)
+	g.DisplayItemInfo = true
+	if to == inventory {
+		fmt.Fprint(screen, render("text", g.str("itemAdded", vars{"item": g.named(moved.ID, moved.Name)})))
+		fmt.Fprintln(screen)
+	}
+	return nil
+}
+
+// checkRules validates the rules in the yaml configuration and returns a description of
+// each problem found.
+func (g *Game) checkRules() []string {
+	var problems []string
+	for _, r := range g.Rules {
+		if g.Dictionary["commands"][r.On] == "" && g.Dictionary["verbs"][r.On] == "" {
+			problems = append(problems, fmt.Sprintf("Rule uses unknown command %s", r.On))
+		}
+		for _, ref := range []string{r.Object, r.Target} {
+			if ref != "" && g.itemByID(toID(ref)) == nil && g.exitByID(toID(ref)) == nil && !g.isNPC(toID(ref)) {
+				problems = append(problems, fmt.Sprintf("Rule refers to unknown object %s", ref))
+			}
+		}
+	}
+	return problems
+}
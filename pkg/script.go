@@ -0,0 +1,472 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A small Lisp for puzzle logic that rules cannot express. e.g
+//
+//	(if (>= (get "pedals") 3)
+//	    (say "You are out of breath.")
+//	    (set "pedals" (+ (get "pedals") 1)))
+//
+// Scripts only reach the game through the builtins in scriptapi.go.
+
+// maxScriptSteps stops runaway scripts, such as an infinite loop written by mistake.
+const maxScriptSteps = 100000
+
+// symbol is a name in a script, looked up in the script environment when evaluated.
+type symbol string
+
+// node is a parsed script expression. Either an atom or a list of expressions.
+type node struct {
+	line   int
+	atom   interface{}
+	list   []*node
+	isList bool
+}
+
+// lambda is a function defined by a script.
+type lambda struct {
+	params []string
+	body   []*node
+	env    *scriptEnv
+}
+
+// builtin is a function provided to scripts by the engine.
+type builtin func(s *scriptRun, args []interface{}) (interface{}, error)
+
+// scriptEnv holds the variables visible to a script expression.
+type scriptEnv struct {
+	vars   map[string]interface{}
+	parent *scriptEnv
+}
+
+// scriptError is a script parse or runtime error, reported with the file and line it occurred on.
+type scriptError struct {
+	file string
+	line int
+	msg  string
+}
+
+func (e *scriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.file, e.line, e.msg)
+}
+
+// script is a script attached to the game or an object, with the line of the file it was read
+// from that it starts on, so errors can be reported against the file.
+type script struct {
+	Source string
+	line   int
+}
+
+// UnmarshalYAML reads a script and the line it starts on. The text of a block scalar, such as
+// "use: |", starts on the line after it.
+func (s *script) UnmarshalYAML(value *yaml.Node) error {
+	s.line = value.Line
+	if value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		s.line++
+	}
+	return value.Decode(&s.Source)
+}
+
+// MarshalYAML writes a script as its source alone.
+func (s script) MarshalYAML() (interface{}, error) {
+	return s.Source, nil
+}
+
+// scriptSource is the text of a script and where it came from.
+type scriptSource struct {
+	file   string
+	line   int
+	source string
+}
+
+// scriptRun is a single run of a script against a game.
+type scriptRun struct {
+	g           *Game
+	file        string
+	line        int
+	steps       int
+	fallThrough bool
+}
+
+// newScriptEnv returns an environment inside a parent environment.
+func newScriptEnv(parent *scriptEnv) *scriptEnv {
+	return &scriptEnv{vars: make(map[string]interface{}), parent: parent}
+}
+
+// lookup returns the value of a variable and the environment it was found in.
+func (e *scriptEnv) lookup(name string) (interface{}, *scriptEnv) {
+	for env := e; env != nil; env = env.parent {
+		if value, ok := env.vars[name]; ok {
+			return value, env
+		}
+	}
+	return nil, nil
+}
+
+// parseScript parses the text of a script into a list of expressions.
+func parseScript(src scriptSource) ([]*node, error) {
+	tokens, err := tokenise(src)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*node
+	for len(tokens) > 0 {
+		var n *node
+		n, tokens, err = parseNode(src, tokens)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// token is a single word, bracket or string in a script.
+type token struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+// tokenise splits the text of a script into tokens, dropping comments.
+func tokenise(src scriptSource) ([]token, error) {
+	var tokens []token
+	runes := []rune(src.source)
+	line := src.line
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+		case unicode.IsSpace(r):
+		case r == ';':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			i--
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{text: string(r), line: line})
+		case r == '"':
+			start := line
+			var text []rune
+			for i++; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					if runes[i] == 'n' {
+						text = append(text, '\n')
+						continue
+					}
+				}
+				if runes[i] == '\n' {
+					line++
+				}
+				text = append(text, runes[i])
+			}
+			if i >= len(runes) {
+				return nil, &scriptError{src.file, start, "unterminated string"}
+			}
+			tokens = append(tokens, token{text: string(text), line: start, quoted: true})
+		default:
+			var text []rune
+			for ; i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("();\"", runes[i]); i++ {
+				text = append(text, runes[i])
+			}
+			i--
+			tokens = append(tokens, token{text: string(text), line: line})
+		}
+	}
+	return tokens, nil
+}
+
+// parseNode parses the expression at the start of a list of tokens and returns the remaining tokens.
+func parseNode(src scriptSource, tokens []token) (*node, []token, error) {
+	t := tokens[0]
+	tokens = tokens[1:]
+	if t.quoted {
+		return &node{line: t.line, atom: t.text}, tokens, nil
+	}
+	switch t.text {
+	case ")":
+		return nil, nil, &scriptError{src.file, t.line, "unexpected )"}
+	case "(":
+		n := &node{line: t.line, isList: true}
+		for {
+			if len(tokens) == 0 {
+				return nil, nil, &scriptError{src.file, t.line, "missing )"}
+			}
+			if tokens[0].text == ")" && !tokens[0].quoted {
+				return n, tokens[1:], nil
+			}
+			var child *node
+			var err error
+			child, tokens, err = parseNode(src, tokens)
+			if err != nil {
+				return nil, nil, err
+			}
+			n.list = append(n.list, child)
+		}
+	case "true":
+		return &node{line: t.line, atom: true}, tokens, nil
+	case "false":
+		return &node{line: t.line, atom: false}, tokens, nil
+	case "nil":
+		return &node{line: t.line, atom: nil}, tokens, nil
+	}
+	if number, err := strconv.Atoi(t.text); err == nil {
+		return &node{line: t.line, atom: number}, tokens, nil
+	}
+	return &node{line: t.line, atom: symbol(t.text)}, tokens, nil
+}
+
+// fail returns a script error at the line currently being run.
+func (s *scriptRun) fail(format string, args ...interface{}) error {
+	return &scriptError{s.file, s.line, fmt.Sprintf(format, args...)}
+}
+
+// truthy returns if a script value counts as true. Only false and nil are false.
+func truthy(value interface{}) bool {
+	return value != nil && value != false
+}
+
+// eval evaluates a single expression.
+func (s *scriptRun) eval(n *node, env *scriptEnv) (interface{}, error) {
+	s.line = n.line
+	s.steps++
+	if s.steps > maxScriptSteps {
+		return nil, s.fail("script took too many steps")
+	}
+	if !n.isList {
+		name, ok := n.atom.(symbol)
+		if !ok {
+			return n.atom, nil
+		}
+		value, found := env.lookup(string(name))
+		if found == nil {
+			return nil, s.fail("unknown name %s", name)
+		}
+		return value, nil
+	}
+	if len(n.list) == 0 {
+		return nil, nil
+	}
+	if name, ok := n.list[0].atom.(symbol); ok && !n.list[0].isList {
+		if form, ok := specialForms[string(name)]; ok {
+			return form(s, n, env)
+		}
+	}
+	fn, err := s.eval(n.list[0], env)
+	if err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	for _, arg := range n.list[1:] {
+		value, err := s.eval(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	s.line = n.line
+	return s.call(fn, args)
+}
+
+// call calls a builtin or script defined function with a list of arguments.
+func (s *scriptRun) call(fn interface{}, args []interface{}) (interface{}, error) {
+	switch f := fn.(type) {
+	case builtin:
+		return f(s, args)
+	case *lambda:
+		if len(args) != len(f.params) {
+			return nil, s.fail("function expects %d arguments, got %d", len(f.params), len(args))
+		}
+		env := newScriptEnv(f.env)
+		for i, param := range f.params {
+			env.vars[param] = args[i]
+		}
+		return s.evalBody(f.body, env)
+	}
+	return nil, s.fail("%v is not a function", fn)
+}
+
+// evalBody evaluates a list of expressions and returns the value of the last.
+func (s *scriptRun) evalBody(body []*node, env *scriptEnv) (interface{}, error) {
+	var result interface{}
+	for _, n := range body {
+		var err error
+		result, err = s.eval(n, env)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// specialForm is an expression whose arguments are not all evaluated before it is run.
+type specialForm func(s *scriptRun, n *node, env *scriptEnv) (interface{}, error)
+
+var specialForms map[string]specialForm
+
+func init() {
+	specialForms = map[string]specialForm{
+		"if":     formIf,
+		"do":     formDo,
+		"and":    formAnd,
+		"or":     formOr,
+		"let":    formLet,
+		"define": formDefine,
+		"lambda": formLambda,
+		"set!":   formSetLocal,
+	}
+}
+
+// formIf is (if test then [else]).
+func formIf(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	if len(n.list) < 3 || len(n.list) > 4 {
+		return nil, s.fail("if expects a test, a then and an optional else")
+	}
+	test, err := s.eval(n.list[1], env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(test) {
+		return s.eval(n.list[2], env)
+	}
+	if len(n.list) == 4 {
+		return s.eval(n.list[3], env)
+	}
+	return nil, nil
+}
+
+// formDo is (do expr...), evaluating each expression in turn.
+func formDo(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	return s.evalBody(n.list[1:], env)
+}
+
+// formAnd is (and expr...), stopping at the first false expression.
+func formAnd(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	var result interface{} = true
+	for _, arg := range n.list[1:] {
+		var err error
+		result, err = s.eval(arg, env)
+		if err != nil || !truthy(result) {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// formOr is (or expr...), stopping at the first true expression.
+func formOr(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	var result interface{} = false
+	for _, arg := range n.list[1:] {
+		var err error
+		result, err = s.eval(arg, env)
+		if err != nil || truthy(result) {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// formLet is (let ((name value)...) body...).
+func formLet(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	if len(n.list) < 2 || !n.list[1].isList {
+		return nil, s.fail("let expects a list of bindings")
+	}
+	local := newScriptEnv(env)
+	for _, binding := range n.list[1].list {
+		if !binding.isList || len(binding.list) != 2 {
+			return nil, s.fail("let binding must be (name value)")
+		}
+		name, ok := binding.list[0].atom.(symbol)
+		if !ok {
+			return nil, s.fail("let binding must be (name value)")
+		}
+		value, err := s.eval(binding.list[1], env)
+		if err != nil {
+			return nil, err
+		}
+		local.vars[string(name)] = value
+	}
+	return s.evalBody(n.list[2:], local)
+}
+
+// formDefine is (define name value) or (define (name params...) body...).
+func formDefine(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	if len(n.list) < 3 {
+		return nil, s.fail("define expects a name and a value")
+	}
+	if n.list[1].isList {
+		names, err := s.names(n.list[1].list)
+		if err != nil || len(names) == 0 {
+			return nil, s.fail("define expects (name params...)")
+		}
+		env.vars[names[0]] = &lambda{params: names[1:], body: n.list[2:], env: env}
+		return nil, nil
+	}
+	name, ok := n.list[1].atom.(symbol)
+	if !ok {
+		return nil, s.fail("define expects a name")
+	}
+	value, err := s.eval(n.list[2], env)
+	if err != nil {
+		return nil, err
+	}
+	env.vars[string(name)] = value
+	return nil, nil
+}
+
+// formLambda is (lambda (params...) body...).
+func formLambda(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	if len(n.list) < 3 || !n.list[1].isList {
+		return nil, s.fail("lambda expects a list of parameters and a body")
+	}
+	params, err := s.names(n.list[1].list)
+	if err != nil {
+		return nil, err
+	}
+	return &lambda{params: params, body: n.list[2:], env: env}, nil
+}
+
+// formSetLocal is (set! name value), changing a variable that already exists.
+func formSetLocal(s *scriptRun, n *node, env *scriptEnv) (interface{}, error) {
+	if len(n.list) != 3 {
+		return nil, s.fail("set! expects a name and a value")
+	}
+	name, ok := n.list[1].atom.(symbol)
+	if !ok {
+		return nil, s.fail("set! expects a name")
+	}
+	_, found := env.lookup(string(name))
+	if found == nil {
+		return nil, s.fail("unknown name %s", name)
+	}
+	value, err := s.eval(n.list[2], env)
+	if err != nil {
+		return nil, err
+	}
+	found.vars[string(name)] = value
+	return value, nil
+}
+
+// names converts a list of symbol expressions into their names.
+func (s *scriptRun) names(nodes []*node) ([]string, error) {
+	var names []string
+	for _, n := range nodes {
+		name, ok := n.atom.(symbol)
+		if !ok || n.isList {
+			return nil, s.fail("expected a name")
+		}
+		names = append(names, string(name))
+	}
+	return names, nil
+}
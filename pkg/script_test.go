@@ -0,0 +1,141 @@
+package textgame
+
+import (
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+// evalTestScript runs a script read from line 10 of test.yaml and returns the value of its
+// last expression.
+func evalTestScript(t *testing.T, source string) (interface{}, error) {
+	t.Helper()
+	g := &Game{}
+	if err := g.loadScripts(); err != nil {
+		t.Fatal(err)
+	}
+	nodes, err := parseScript(scriptSource{file: "test.yaml", line: 10, source: source})
+	if err != nil {
+		return nil, err
+	}
+	s := &scriptRun{g: g, file: "test.yaml", line: 10}
+	return s.evalBody(nodes, newScriptEnv(g.scriptGlobals))
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`(say "hello"`, "test.yaml:10: missing )"},
+		{"(say \"hello\")\n\n)", "test.yaml:12: unexpected )"},
+		{"(say\n  \"hello)", "test.yaml:11: unterminated string"},
+		{"; a comment (\n(if true\n  (say \"a\")", "test.yaml:11: missing )"},
+		{"(say \"one\ntwo\")\n)", "test.yaml:12: unexpected )"},
+	}
+	for _, test := range tests {
+		_, err := parseScript(scriptSource{file: "test.yaml", line: 10, source: test.source})
+		if err == nil || err.Error() != test.want {
+			t.Errorf("parseScript(%q) error = %v, want %s", test.source, err, test.want)
+		}
+	}
+}
+
+func TestScriptLine(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want int
+	}{
+		{"use: (say \"a\")\n", 1},
+		{"open: x\nuse: |\n  (say \"a\")\n", 3},
+		{"use: >\n  (say \"a\")\n", 2},
+		{"use:\n  \"(say a)\"\n", 2},
+	}
+	for _, test := range tests {
+		var scripts map[string]script
+		if err := yaml.Unmarshal([]byte(test.yaml), &scripts); err != nil {
+			t.Fatal(err)
+		}
+		if got := scripts["use"].line; got != test.want {
+			t.Errorf("line of script in %q = %d, want %d", test.yaml, got, test.want)
+		}
+	}
+}
+
+func TestScriptValues(t *testing.T) {
+	tests := []struct {
+		source string
+		want   interface{}
+	}{
+		{"(+ 1 2 3)", 6},
+		{"(- 10 4 1)", 5},
+		{"(* 2 3)", 6},
+		{"(/ 7 2)", 3},
+		{"(% 7 2)", 1},
+		{"(< 1 2)", true},
+		{"(>= 2 3)", false},
+		{`(= "a" "a" "a")`, true},
+		{`(= 1 "1")`, false},
+		{"(!= 1 2)", true},
+		{"(= nil nil)", true},
+		{"(not nil)", true},
+		{"(if false 1 2)", 2},
+		{"(if false 1)", nil},
+		{"(let ((x 2) (y 3)) (* x y))", 6},
+		{"(define (square x) (* x x))\n(square 4)", 16},
+		{"(define n 1)\n(set! n (+ n 1))\nn", 2},
+		{"(and 1 false (undefined))", false},
+		{"(or nil 2 (undefined))", 2},
+	}
+	for _, test := range tests {
+		got, err := evalTestScript(t, test.source)
+		if err != nil || got != test.want {
+			t.Errorf("%q = %v, %v, want %v", test.source, got, err, test.want)
+		}
+	}
+}
+
+func TestScriptErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`(+ 1 "a")`, "test.yaml:10: argument 2 must be a number, got a"},
+		{"(+)", "test.yaml:10: missing argument 1"},
+		{"\n(< 1 true)", "test.yaml:11: argument 2 must be a number, got true"},
+		{"(> 1)", "test.yaml:10: comparison expects two arguments"},
+		{"(/ 1 0)", "test.yaml:10: division by zero"},
+		{"(% 1 0)", "test.yaml:10: division by zero"},
+		{"(= + +)", "test.yaml:10: = cannot compare functions or lists"},
+		{"\n\n(!= say say)", "test.yaml:12: = cannot compare functions or lists"},
+		{`(= 1 say)`, "test.yaml:10: = cannot compare functions or lists"},
+		{"(1 2)", "test.yaml:10: 1 is not a function"},
+		{"(define (f x) x)\n(f)", "test.yaml:11: function expects 1 arguments, got 0"},
+		{"(say\n  missing)", "test.yaml:11: unknown name missing"},
+		{"(set! missing 1)", "test.yaml:10: unknown name missing"},
+	}
+	for _, test := range tests {
+		_, err := evalTestScript(t, test.source)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%q error = %v, want %s", test.source, err, test.want)
+		}
+	}
+}
+
+func TestScriptSteps(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"(define (loop) (loop))\n(loop)", "script took too many steps"},
+		{"(define (count n) (count (+ n 1)))\n(count 0)", "script took too many steps"},
+	}
+	for _, test := range tests {
+		_, err := evalTestScript(t, test.source)
+		if err == nil || err.(*scriptError).msg != test.want {
+			t.Errorf("%q error = %v, want %s", test.source, err, test.want)
+		}
+	}
+	if _, err := evalTestScript(t, "(define (count n) (if (< n 100) (count (+ n 1)) n))\n(count 0)"); err != nil {
+		t.Errorf("script within the step limit failed: %v", err)
+	}
+}
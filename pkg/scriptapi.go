@@ -0,0 +1,423 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+)
+
+// scriptBuiltins are the functions available to every script. They are the only way
+// a script can read or change the game.
+var scriptBuiltins = map[string]builtin{
+	"say":      scriptSay,
+	"str":      scriptStr,
+	"get":      scriptGet,
+	"set":      scriptSet,
+	"has":      scriptHas,
+	"here":     scriptHere,
+	"locked":   scriptLocked,
+	"room":     scriptRoom,
+	"move":     scriptMove,
+	"reveal":   scriptAction(func(id string) action { return action{Reveal: id} }),
+	"unlock":   scriptAction(func(id string) action { return action{Unlock: id} }),
+	"lock":     scriptLock,
+	"teleport": scriptTeleport,
+	"end":      scriptEnd,
+	"continue": scriptContinue,
+	"not":      scriptNot,
+	"=":        scriptEquals,
+	"!=":       scriptNotEquals,
+	"+":        scriptArithmetic(func(a, b int) int { return a + b }),
+	"-":        scriptArithmetic(func(a, b int) int { return a - b }),
+	"*":        scriptArithmetic(func(a, b int) int { return a * b }),
+	"/":        scriptDivide(func(a, b int) int { return a / b }),
+	"%":        scriptDivide(func(a, b int) int { return a % b }),
+	"<":        scriptCompare(func(a, b int) bool { return a < b }),
+	">":        scriptCompare(func(a, b int) bool { return a > b }),
+	"<=":       scriptCompare(func(a, b int) bool { return a <= b }),
+	">=":       scriptCompare(func(a, b int) bool { return a >= b }),
+}
+
+// loadScripts creates the global script environment and runs every file in ScriptFiles,
+// so the functions they define can be called by the scripts on objects.
+func (g *Game) loadScripts() error {
+	g.scriptGlobals = newScriptEnv(nil)
+	for name, fn := range scriptBuiltins {
+		g.scriptGlobals.vars[name] = fn
+	}
+	for _, path := range g.ScriptFiles {
+		text, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("Unable to read script file %s", path)
+		}
+		_, err = g.runScript(scriptSource{file: path, line: 1, source: string(text)}, g.scriptGlobals)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locate returns a script attached to an object in the game file, along with the game file
+// and line it starts on, so errors can be reported against the game file.
+func (g *Game) locate(s script) scriptSource {
+	return scriptSource{file: g.file, line: s.line, source: s.Source}
+}
+
+// runScript parses and runs a script in an environment, returning the value of its last expression.
+func (g *Game) runScript(src scriptSource, env *scriptEnv) (*scriptRun, error) {
+	nodes, err := parseScript(src)
+	if err != nil {
+		return nil, err
+	}
+	s := &scriptRun{g: g, file: src.file, line: src.line}
+	_, err = s.evalBody(nodes, env)
+	return s, err
+}
+
+// runScripts runs the first script attached to a command. Scripts on the object of the command
+// are checked first, then scripts on the current room, then the game wide scripts.
+// Returns false if no script was found, or the script called continue to let the command run.
+func (g *Game) runScripts(command string, object string, target string) (bool, error) {
+	ids := g.visibleIDs(object)
+	objectID := object
+	if len(ids) > 0 {
+		objectID = ids[0]
+	}
+	var source script
+	for _, id := range ids {
+		if item := g.getItemByID(id); item != nil && item.Scripts[command].Source != "" {
+			source, objectID = item.Scripts[command], id
+			break
+		}
+		if exit := g.CurrentRoom.getExitByID(id); exit != nil && exit.Scripts[command].Source != "" {
+			source, objectID = exit.Scripts[command], id
+			break
+		}
+	}
+	if source.Source == "" {
+		source = g.CurrentRoom.Scripts[command]
+	}
+	if source.Source == "" {
+		source = g.Scripts[command]
+	}
+	if source.Source == "" {
+		return false, nil
+	}
+	targetID := target
+	if ids := g.visibleIDs(target); len(ids) > 0 {
+		targetID = ids[0]
+	}
+	env := newScriptEnv(g.scriptGlobals)
+	env.vars["verb"] = command
+	env.vars["object"] = objectID
+	env.vars["target"] = targetID
+	s, err := g.runScript(g.locate(source), env)
+	if err != nil {
+		return true, err
+	}
+	return !s.fallThrough, nil
+}
+
+// allScripts returns every script attached to the game or an object.
+func (g *Game) allScripts() []script {
+	var sources []script
+	add := func(scripts map[string]script) {
+		for _, source := range scripts {
+			sources = append(sources, source)
+		}
+	}
+	add(g.Scripts)
+	walkItems(g.Player, func(i *item) { add(i.Scripts) })
+	for r := range g.Rooms {
+		add(g.Rooms[r].Scripts)
+		walkItems(&g.Rooms[r], func(i *item) { add(i.Scripts) })
+		for n := range g.Rooms[r].NPCs {
+			walkItems(&g.Rooms[r].NPCs[n], func(i *item) { add(i.Scripts) })
+		}
+		for _, exit := range g.Rooms[r].Exits {
+			add(exit.Scripts)
+		}
+	}
+	return sources
+}
+
+// checkScripts parses every script attached to an object and returns a description of each
+// problem found.
+func (g *Game) checkScripts() []string {
+	var problems []string
+	for _, source := range g.allScripts() {
+		if _, err := parseScript(g.locate(source)); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// stringArg returns an argument to a builtin as a string.
+func (s *scriptRun) stringArg(args []interface{}, index int) (string, error) {
+	if index >= len(args) {
+		return "", s.fail("missing argument %d", index+1)
+	}
+	value, ok := args[index].(string)
+	if !ok {
+		return "", s.fail("argument %d must be a string, got %v", index+1, args[index])
+	}
+	return value, nil
+}
+
+// intArg returns an argument to a builtin as an int.
+func (s *scriptRun) intArg(args []interface{}, index int) (int, error) {
+	if index >= len(args) {
+		return 0, s.fail("missing argument %d", index+1)
+	}
+	value, ok := args[index].(int)
+	if !ok {
+		return 0, s.fail("argument %d must be a number, got %v", index+1, args[index])
+	}
+	return value, nil
+}
+
+// scriptString converts a script value into text.
+func scriptString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// scriptSay is (say text...), printing its arguments.
+func scriptSay(s *scriptRun, args []interface{}) (interface{}, error) {
+	text, _ := scriptStr(s, args)
+	fmt.Fprintln(screen, render("text", s.g.text(text.(string))))
+	return nil, nil
+}
+
+// scriptStr is (str value...), joining its arguments into a string.
+func scriptStr(s *scriptRun, args []interface{}) (interface{}, error) {
+	var text string
+	for _, arg := range args {
+		text += scriptString(arg)
+	}
+	return text, nil
+}
+
+// scriptGet is (get "variable"), returning a game variable or nil.
+func scriptGet(s *scriptRun, args []interface{}) (interface{}, error) {
+	name, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.g.variable(name), nil
+}
+
+// scriptSet is (set "variable" value), changing a game variable.
+func scriptSet(s *scriptRun, args []interface{}) (interface{}, error) {
+	name, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 {
+		return nil, s.fail("set expects a variable and a value")
+	}
+	if err := s.g.setVariable(name, args[1]); err != nil {
+		return nil, s.fail("%s", err)
+	}
+	return args[1], nil
+}
+
+// scriptHas is (has "item-id"), returning if the item is in the player's inventory.
+func scriptHas(s *scriptRun, args []interface{}) (interface{}, error) {
+	id, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return getItemByID(toID(id), s.g.Player) != nil, nil
+}
+
+// scriptHere is (here "object-id"), returning if the item or exit is visible to the player.
+func scriptHere(s *scriptRun, args []interface{}) (interface{}, error) {
+	id, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	id = toID(id)
+	return s.g.getItemByID(id) != nil || s.g.CurrentRoom.getExitByID(id) != nil, nil
+}
+
+// scriptLocked is (locked "object-id"), returning if the item or exit is locked.
+func scriptLocked(s *scriptRun, args []interface{}) (interface{}, error) {
+	id, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.g.isLocked(toID(id)), nil
+}
+
+// scriptRoom is (room), returning the id of the current room.
+func scriptRoom(s *scriptRun, args []interface{}) (interface{}, error) {
+	return s.g.CurrentRoom.ID, nil
+}
+
+// scriptMove is (move "item-id" to), where to is "inventory", an item id or a room id.
+func scriptMove(s *scriptRun, args []interface{}) (interface{}, error) {
+	id, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	a := action{Move: id}
+	if len(args) > 1 {
+		switch to := args[1].(type) {
+		case int:
+			a.ToRoom = to
+		case string:
+			a.To = to
+		default:
+			return nil, s.fail("move expects an item, \"inventory\" or a room id")
+		}
+	}
+	if err := s.g.doAction(a, "Script"); err != nil {
+		return nil, s.fail("%s", err)
+	}
+	return nil, nil
+}
+
+// scriptAction returns a builtin taking an object id that carries out a rule action.
+func scriptAction(build func(id string) action) builtin {
+	return func(s *scriptRun, args []interface{}) (interface{}, error) {
+		id, err := s.stringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.g.doAction(build(id), "Script"); err != nil {
+			return nil, s.fail("%s", err)
+		}
+		return nil, nil
+	}
+}
+
+// scriptLock is (lock "object-id"), locking the item or exit without needing a key.
+func scriptLock(s *scriptRun, args []interface{}) (interface{}, error) {
+	id, err := s.stringArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	id = toID(id)
+	if item := s.g.itemByID(id); item != nil {
+		item.Locked = true
+	} else if exit := s.g.exitByID(id); exit != nil {
+		exit.Locked = true
+		s.g.syncExit(exit)
+	} else {
+		return nil, s.fail("unknown object %s", id)
+	}
+	return nil, nil
+}
+
+// scriptTeleport is (teleport room-id), moving the player to another room.
+func scriptTeleport(s *scriptRun, args []interface{}) (interface{}, error) {
+	id, err := s.intArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.g.doAction(action{Teleport: id}, "Script"); err != nil {
+		return nil, s.fail("%s", err)
+	}
+	return nil, nil
+}
+
+// scriptEnd is (end "text"), ending the game.
+func scriptEnd(s *scriptRun, args []interface{}) (interface{}, error) {
+	text, _ := scriptStr(s, args)
+	return nil, s.g.doAction(action{End: text.(string)}, "Script")
+}
+
+// scriptContinue is (continue), letting the command run as normal once the script finishes.
+func scriptContinue(s *scriptRun, args []interface{}) (interface{}, error) {
+	s.fallThrough = true
+	return nil, nil
+}
+
+// scriptNot is (not value).
+func scriptNot(s *scriptRun, args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, s.fail("not expects one argument")
+	}
+	return !truthy(args[0]), nil
+}
+
+// scriptEquals is (= a b...), true if every argument is equal. Only values such as numbers,
+// strings and booleans can be compared, not functions or lists.
+func scriptEquals(s *scriptRun, args []interface{}) (interface{}, error) {
+	for _, arg := range args {
+		if t := reflect.TypeOf(arg); t != nil && !t.Comparable() {
+			return nil, s.fail("= cannot compare functions or lists")
+		}
+	}
+	for i := 1; i < len(args); i++ {
+		if args[i] != args[0] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// scriptNotEquals is (!= a b).
+func scriptNotEquals(s *scriptRun, args []interface{}) (interface{}, error) {
+	equal, err := scriptEquals(s, args)
+	if err != nil {
+		return nil, err
+	}
+	return !equal.(bool), nil
+}
+
+// scriptArithmetic returns a builtin applying an operation across all of its number arguments.
+func scriptArithmetic(op func(a, b int) int) builtin {
+	return func(s *scriptRun, args []interface{}) (interface{}, error) {
+		result, err := s.intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(args); i++ {
+			n, err := s.intArg(args, i)
+			if err != nil {
+				return nil, err
+			}
+			result = op(result, n)
+		}
+		return result, nil
+	}
+}
+
+// scriptDivide returns a builtin like scriptArithmetic that fails on division by zero.
+func scriptDivide(op func(a, b int) int) builtin {
+	arithmetic := scriptArithmetic(op)
+	return func(s *scriptRun, args []interface{}) (interface{}, error) {
+		for i := 1; i < len(args); i++ {
+			if args[i] == 0 {
+				return nil, s.fail("division by zero")
+			}
+		}
+		return arithmetic(s, args)
+	}
+}
+
+// scriptCompare returns a builtin comparing two numbers.
+func scriptCompare(op func(a, b int) bool) builtin {
+	return func(s *scriptRun, args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, s.fail("comparison expects two arguments")
+		}
+		a, err := s.intArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := s.intArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return op(a, b), nil
+	}
+}
@@ -0,0 +1,166 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+)
+
+// itemState is one of the named states an item can be in, declared in the yaml configuration.
+// Entering a state replaces the item's name, description and use string where they are set,
+// and sets any of the item's flags that are given.
+type itemState struct {
+	Name        string
+	Description string
+	UseString   string
+	Open        *bool
+	Locked      *bool
+	Takeable    *bool
+	Useable     *bool
+	Lit         *bool
+	Transitions []transition
+}
+
+// transition moves an item to the state To when a command or custom verb is used on it.
+// With names the other item the command must be used with, if any. A transition with no To
+// prints its Text and leaves the item as it is.
+type transition struct {
+	On   string
+	With string
+	To   string
+	Text string
+	When []condition
+}
+
+// applyState copies the values set in a state onto an item.
+// Unlocking an item with an UnlockName applies a state in the same way.
+func applyState(i *item, s itemState) {
+	if s.Name != "" {
+		i.Name = s.Name
+	}
+	if s.Description != "" {
+		i.Description = s.Description
+	}
+	if s.UseString != "" {
+		i.UseString = s.UseString
+	}
+	setFlag(&i.Open, s.Open)
+	setFlag(&i.Locked, s.Locked)
+	setFlag(&i.Takeable, s.Takeable)
+	setFlag(&i.Useable, s.Useable)
+	setFlag(&i.Lit, s.Lit)
+}
+
+// setFlag is a helper function to set a flag to a value, if the value is given.
+func setFlag(flag *bool, value *bool) {
+	if value != nil {
+		*flag = *value
+	}
+}
+
+// setState moves an item into one of its states.
+func (g *Game) setState(i *item, name string) error {
+	s, ok := i.States[name]
+	if !ok {
+		return fmt.Errorf("Item %s has no state %s", i.ID, name)
+	}
+	wasLit := g.isLit(g.CurrentRoom)
+	i.State = name
+	applyState(i, s)
+	g.indexNames()
+	g.refreshLight(wasLit)
+	return nil
+}
+
+// applyStates applies the current state of every item, so the item matches its state when
+// the game is loaded.
+func (g *Game) applyStates() {
+	apply := func(i *item) {
+		if s, ok := i.States[i.State]; ok {
+			applyState(i, s)
+		}
+	}
+	walkItems(g.Player, apply)
+	for r := range g.Rooms {
+		walkItems(&g.Rooms[r], apply)
+		for n := range g.Rooms[r].NPCs {
+			walkItems(&g.Rooms[r].NPCs[n], apply)
+		}
+	}
+}
+
+// runTransitions carries out the first transition matching a command on the object, or on the
+// target when the command uses the object with it. Returns if a transition was found.
+func (g *Game) runTransitions(command string, object string, target string) (bool, error) {
+	subject := g.getItemByName(object)
+	var other *item
+	if target != "" {
+		other = g.getItemByName(target)
+		if other == nil {
+			return false, nil
+		}
+	}
+	if subject == nil {
+		return false, nil
+	}
+	handled, err := g.transition(subject, command, other)
+	if handled || other == nil {
+		return handled, err
+	}
+	return g.transition(other, command, subject)
+}
+
+// transition carries out the first transition from an item's current state matching a command,
+// and the item it is used with.
+func (g *Game) transition(i *item, command string, with *item) (bool, error) {
+	for _, t := range i.States[i.State].Transitions {
+		if t.On != command || (t.With == "") != (with == nil) || !g.met(t.When) {
+			continue
+		}
+		if with != nil && !with.is(t.With) {
+			continue
+		}
+		if t.Text != "" {
+			fmt.Fprintln(screen, render("text", g.text(t.Text)))
+		}
+		if t.To == "" {
+			return true, nil
+		}
+		return true, g.setState(i, t.To)
+	}
+	return false, nil
+}
+
+// checkStates validates the states of every item and returns a description of each problem found.
+func (g *Game) checkStates() []string {
+	var problems []string
+	check := func(i *item) {
+		if len(i.States) == 0 {
+			return
+		}
+		if _, ok := i.States[i.State]; !ok {
+			problems = append(problems, fmt.Sprintf("Item %s is in unknown state %s", i.ID, i.State))
+		}
+		for _, s := range i.States {
+			for _, t := range s.Transitions {
+				if _, ok := i.States[t.To]; t.To != "" && !ok {
+					problems = append(problems, fmt.Sprintf("Item %s has a transition to unknown state %s", i.ID, t.To))
+				}
+				if !g.isCommand(t.On) {
+					problems = append(problems, fmt.Sprintf("Item %s has a transition on unknown command %s", i.ID, t.On))
+				}
+				if t.With != "" && g.itemByID(toID(t.With)) == nil {
+					problems = append(problems, fmt.Sprintf("Item %s has a transition with unknown item %s", i.ID, t.With))
+				}
+			}
+		}
+	}
+	walkItems(g.Player, check)
+	for r := range g.Rooms {
+		walkItems(&g.Rooms[r], check)
+		for n := range g.Rooms[r].NPCs {
+			walkItems(&g.Rooms[r].NPCs[n], check)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,130 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"strings"
+)
+
+// vars are the named values substituted into the placeholders of a template.
+// e.g vars{"item": "Guitar"} for the template "You take the {item}."
+type vars map[string]interface{}
+
+// flagPrefix introduces a placeholder for the value of a game variable, e.g {flag:pedals}.
+const flagPrefix = "flag:"
+
+// expand substitutes the placeholders in a template, names in braces such as {item}, with
+// their values. Literal braces are written twice, {{ and }}. Besides the values provided,
+// every template may use {player}, {game}, {room}, {turn}, {time} and {flag:name}.
+// Placeholders may also select text by gender or number, see selectCase.
+// Values are inserted as they are and never expanded themselves, so text typed by the player
+// or a "%" in a description cannot change the result. The marks of markup in values are
+// escaped, so they are not styled when the text is rendered.
+func (g *Game) expand(template string, v vars) string {
+	if !strings.ContainsAny(template, "{}") {
+		return template
+	}
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if (c == '{' || c == '}') && i+1 < len(template) && template[i+1] == c {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if c != '{' {
+			b.WriteByte(c)
+			continue
+		}
+		end := closingBrace(template, i)
+		if end < 0 {
+			b.WriteString(template[i:])
+			break
+		}
+		name := strings.TrimSpace(template[i+1 : end])
+		if value, ok := g.placeholder(name, v); ok {
+			b.WriteString(value)
+		} else {
+			logger.Printf("%s: unknown placeholder {%s} in %q", g.Language, name, template)
+			b.WriteString(template[i : end+1])
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// closingBrace returns the index of the brace closing the one at start, or -1 if there is none.
+func closingBrace(template string, start int) int {
+	depth := 0
+	for i := start; i < len(template); i++ {
+		switch template[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// placeholder returns the value of a placeholder: a name, a name with a grammatical attribute
+// such as {item.definite}, or a select or plural of a name's value.
+func (g *Game) placeholder(content string, v vars) (string, bool) {
+	if parts := strings.SplitN(content, ",", 3); len(parts) == 3 {
+		value, ok := g.value(strings.TrimSpace(parts[0]), v)
+		if !ok {
+			return "", false
+		}
+		return g.selectCase(value, strings.TrimSpace(parts[1]), parts[2], v)
+	}
+	name, attribute := content, ""
+	if dot := strings.IndexByte(content, '.'); dot >= 0 && !strings.HasPrefix(content, flagPrefix) {
+		name, attribute = content[:dot], content[dot+1:]
+	}
+	value, ok := g.value(name, v)
+	if !ok {
+		return "", false
+	}
+	if attribute != "" {
+		n, isNoun := value.(noun)
+		if !isNoun {
+			return "", false
+		}
+		text, ok := g.attribute(n, attribute)
+		return markupMarks.Replace(text), ok
+	}
+	return markupMarks.Replace(fmt.Sprint(value)), true
+}
+
+// value returns the value named by a placeholder, from the values provided or the game itself.
+func (g *Game) value(name string, v vars) (interface{}, bool) {
+	if value, ok := v[name]; ok {
+		return value, true
+	}
+	switch name {
+	case "player":
+		if g.Player != nil {
+			return noun{playerID, g.Player.Name}, true
+		}
+	case "game":
+		return g.message(g.Name), true
+	case "room":
+		if g.CurrentRoom != nil {
+			return g.message(g.CurrentRoom.Name), true
+		}
+	case "turn":
+		return g.Turn, true
+	case "time":
+		return g.clock(), true
+	}
+	if strings.HasPrefix(name, flagPrefix) {
+		if value := g.variable(strings.TrimPrefix(name, flagPrefix)); value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
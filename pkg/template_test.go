@@ -0,0 +1,46 @@
+package textgame
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	g := &Game{Turn: 3, Variables: map[string]interface{}{"pedals": 2}}
+	tests := []struct {
+		template string
+		v        vars
+		want     string
+	}{
+		{"You take the {item}.", vars{"item": "Guitar"}, "You take the Guitar."},
+		{"No placeholders", nil, "No placeholders"},
+		{"{{item}} and }}", vars{"item": "Guitar"}, "{item} and }"},
+		{"Turn { turn }", nil, "Turn 3"},
+		{"Pedalled {flag:pedals} times", nil, "Pedalled 2 times"},
+
+		// Markup marks in values are escaped, so they are shown as typed.
+		{"Unknown command: {input}", vars{"input": "*take* [all]"}, "Unknown command: **take** [[all]]"},
+		{"You take the {item}.", vars{"item": noun{"x", "[Box]"}}, "You take the [[Box]]."},
+		{"*{item}*", vars{"item": "a*b"}, "*a**b*"},
+
+		// Values are never expanded themselves.
+		{"Unknown command: {input}", vars{"input": "{turn}"}, "Unknown command: {turn}"},
+		{"{input}", vars{"input": "100%"}, "100%"},
+
+		// Placeholders nest inside the cases of a select or plural, escaped only once.
+		{"{n, plural, one {# {item}} other {# {item}s}}", vars{"n": 2, "item": "cat"}, "2 cats"},
+		{"{n, plural, one {# {item}} other {# {item}s}}", vars{"n": 1, "item": "cat"}, "1 cat"},
+		{"{n, plural, other {{item}}}", vars{"n": 2, "item": "*a*"}, "**a**"},
+
+		// Unknown placeholders are left as they are written.
+		{"Hello {nobody}", nil, "Hello {nobody}"},
+		{"Hello {flag:nothing}", nil, "Hello {flag:nothing}"},
+		{"The {item.colour}", vars{"item": noun{"x", "Box"}}, "The {item.colour}"},
+		{"The {item.definite}", vars{"item": "Box"}, "The {item.definite}"},
+		{"{nobody, select, other {text}}", nil, "{nobody, select, other {text}}"},
+		{"{n, choose, other {text}}", vars{"n": 1}, "{n, choose, other {text}}"},
+		{"Hello {item", vars{"item": "Box"}, "Hello {item"},
+	}
+	for _, test := range tests {
+		if got := g.expand(test.template, test.v); got != test.want {
+			t.Errorf("expand(%q, %v) = %q, want %q", test.template, test.v, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"os"
+	"strconv"
+)
+
+// terminalSize returns the number of columns and lines of the terminal the game is shown in,
+// from the COLUMNS and LINES environment variables where the terminal cannot be asked,
+// or 0 if they are unknown.
+func terminalSize() (int, int) {
+	columns, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	lines, _ := strconv.Atoi(os.Getenv("LINES"))
+	return columns, lines
+}
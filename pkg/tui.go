@@ -0,0 +1,221 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tuiMinWidth and tuiMinHeight are the smallest terminal the full screen front end is drawn in.
+const (
+	tuiMinWidth  = 60
+	tuiMinHeight = 12
+)
+
+// tuiMaxStory is the number of lines of the story kept to be drawn in the story pane.
+const tuiMaxStory = 1000
+
+// tui is a full screen front end drawn with ANSI escape codes. A status bar shows the room,
+// time, turn and score above a pane of the story, which is paged as the game writes to it,
+// beside a pane of what can be seen in the room and the inventory. Commands are typed on the
+// bottom line.
+type tui struct {
+	width   int
+	height  int
+	story   []string
+	partial string
+	active  string
+	turn    int
+	saved   output
+}
+
+// PlayTUI plays the game in a full screen terminal interface. When the output is not a
+// terminal, or the terminal is too small, the game is played line by line as with Play.
+func (g *Game) PlayTUI() {
+	width, height := terminalSize()
+	if !isTerminal(os.Stdout) || width < tuiMinWidth || height < tuiMinHeight {
+		g.Play()
+		return
+	}
+	g.run(&tui{width: width, height: height})
+}
+
+// open takes over the screen output, so the game's text is written to the story pane, and
+// switches to the terminal's alternate screen.
+func (t *tui) open() {
+	t.saved = *screen
+	screen.w = t
+	screen.width = t.storyWidth()
+	screen.paged = false
+	screen.clearMode = ClearScrollback
+	fmt.Print("\x1b[?1049h")
+}
+
+// close returns to the terminal's main screen, writing the story since the last command there
+// so the end of the game can still be read.
+func (t *tui) close() {
+	fmt.Print("\x1b[?1049l")
+	screen.w, screen.width, screen.paged, screen.clearMode = t.saved.w, t.saved.width, t.saved.paged, t.saved.clearMode
+	for _, line := range t.story[t.turn:] {
+		fmt.Println(line + reset)
+	}
+}
+
+// show draws the screen with the story written since the last command. When there is more than
+// the story pane can show, it is shown a page at a time, waiting for the player to read each.
+func (t *tui) show(g *Game) {
+	g.showRoom(false)
+	first := t.turn - 1
+	if first < 0 {
+		first = 0
+	}
+	for first+t.rows() < len(t.story) {
+		t.draw(g, first+t.rows())
+		fmt.Printf("\x1b[%d;1H\x1b[2K%s", t.height, screen.more)
+		if _, err := input.ReadString('\n'); err != nil {
+			break
+		}
+		first += t.rows() - 1
+	}
+	t.draw(g, len(t.story))
+}
+
+// command reads a command typed on the bottom line and adds it to the story.
+func (t *tui) command(g *Game) (string, error) {
+	prompt := g.str("command")
+	fmt.Printf("\x1b[%d;1H\x1b[2K%s", t.height, render("prompt", prompt))
+	if screen.transcript != nil {
+		fmt.Fprint(screen.transcript, prompt)
+	}
+	line, err := readLine()
+	if err != nil {
+		return "", err
+	}
+	t.story = append(t.story, render("prompt", prompt)+line)
+	t.turn = len(t.story)
+	fmt.Fprintln(screen)
+	return line, nil
+}
+
+// Write adds the lines the game writes to the story, each starting with the style in effect
+// at the end of the line before, so lines may be drawn apart.
+func (t *tui) Write(p []byte) (int, error) {
+	t.partial += string(p)
+	for {
+		end := strings.IndexByte(t.partial, '\n')
+		if end < 0 {
+			break
+		}
+		var lines []string
+		lines, t.active = continueStyles([]string{t.partial[:end]}, t.active)
+		t.story = append(t.story, lines...)
+		t.partial = t.partial[end+1:]
+	}
+	if len(t.story) > tuiMaxStory {
+		drop := len(t.story) - tuiMaxStory
+		t.story = t.story[drop:]
+		t.turn -= drop
+		if t.turn < 0 {
+			t.turn = 0
+		}
+	}
+	return len(p), nil
+}
+
+// storyWidth returns the number of columns of the story pane, leaving a third of the screen for
+// the room pane.
+func (t *tui) storyWidth() int {
+	side := t.width / 3
+	if side < 24 {
+		side = 24
+	}
+	if side > 40 {
+		side = 40
+	}
+	return t.width - side - 3
+}
+
+// rows returns the number of lines of the story pane.
+func (t *tui) rows() int {
+	return t.height - 3
+}
+
+// draw draws the whole screen: the status bar, the story and room panes and a line above the
+// command line. The story pane shows the lines of the story up to end.
+func (t *tui) draw(g *Game, end int) {
+	if width, height := terminalSize(); width >= tuiMinWidth && height >= tuiMinHeight {
+		t.width, t.height = width, height
+	}
+	storyWidth := t.storyWidth()
+	screen.width = storyWidth
+	rows := t.rows()
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString(t.statusBar(g))
+
+	story := t.story[:end]
+	if len(story) > rows {
+		story = story[len(story)-rows:]
+	}
+	side := t.roomPane(g, t.width-storyWidth-3)
+	for row := 0; row < rows; row++ {
+		fmt.Fprintf(&b, "\x1b[%d;1H", row+2)
+		if row < len(story) {
+			b.WriteString(story[row] + reset)
+		}
+		fmt.Fprintf(&b, "\x1b[%d;%dH│ ", row+2, storyWidth+2)
+		if row < len(side) {
+			b.WriteString(side[row] + reset)
+		}
+	}
+	fmt.Fprintf(&b, "\x1b[%d;1H%s", t.height-1, strings.Repeat("─", t.width))
+	fmt.Print(b.String())
+}
+
+// statusBar returns the status bar: the name of the current room, then the time, turn and, if
+// the game keeps one in a variable named score, the score.
+func (t *tui) statusBar(g *Game) string {
+	left := " " + g.text(g.CurrentRoom.Name)
+	right := g.str("status")
+	if score := g.variable("score"); score != nil {
+		right += "   " + g.str("score", vars{"score": score})
+	}
+	right += " "
+	gap := t.width - visibleLength(left) - visibleLength(right)
+	if gap < 1 {
+		gap = 1
+	}
+	return styles["status"] + left + strings.Repeat(" ", gap) + right + reset
+}
+
+// roomPane returns the lines of the room pane: the lists of what can be seen in the room and
+// the inventory, wrapped to its width.
+func (t *tui) roomPane(g *Game, width int) []string {
+	var lines []string
+	for _, l := range g.roomLists() {
+		lines = append(lines, render("label", strings.TrimSpace(l.label)))
+		options := strings.Split(wrap(strings.TrimSpace(l.options), width), "\n")
+		options, _ = continueStyles(options, "")
+		lines = append(lines, options...)
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// continueStyles starts each line with the style in effect at the end of the line before,
+// beginning with the style active, and returns the style in effect at the end of the last line.
+func continueStyles(lines []string, active string) ([]string, string) {
+	styled := make([]string, len(lines))
+	for i, line := range lines {
+		styled[i] = active + line
+		if codes := escapeCodes.FindAllString(line, -1); len(codes) > 0 {
+			active = codes[len(codes)-1]
+			if active == reset {
+				active = ""
+			}
+		}
+	}
+	return styled, active
+}
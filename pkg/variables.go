@@ -0,0 +1,174 @@
+// Package textgame provides data structures and functions to support
+// development of Text Adventure Games.
+package textgame
+
+import (
+	"fmt"
+)
+
+// condition is a test against a game variable, declared in the yaml configuration.
+// With no Equals, Min or Max the variable must be true.
+// Locked and Takeable instead test the item or exit with that id, and Has tests that the
+// item with that id is in the player's inventory. State tests that the item with that id is
+// in the state named by Equals.
+type condition struct {
+	Var      string
+	Equals   interface{}
+	Min      *int
+	Max      *int
+	Locked   string
+	Takeable string
+	Has      string
+	State    string
+	Not      bool
+}
+
+// effect changes a game variable, declared in the yaml configuration.
+// With no Set or Add the variable is set to true.
+type effect struct {
+	Var string
+	Set interface{}
+	Add int
+}
+
+// reservedVariables are provided by the engine and cannot be set by the world.
+// turn is the number of turns taken and time is the game clock.
+var reservedVariables = []string{"turn", "time"}
+
+// isReserved returns if a variable name is provided by the engine.
+func isReserved(name string) bool {
+	for _, reserved := range reservedVariables {
+		if name == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// variable returns the value of a game variable, including the reserved variables.
+func (g *Game) variable(name string) interface{} {
+	switch name {
+	case "turn":
+		return g.Turn
+	case "time":
+		return g.clock()
+	}
+	return g.Variables[name]
+}
+
+// getBool returns the value of a bool variable, false if it is not set.
+func (g *Game) getBool(name string) bool {
+	value, _ := g.variable(name).(bool)
+	return value
+}
+
+// getInt returns the value of an int variable, 0 if it is not set.
+func (g *Game) getInt(name string) int {
+	value, _ := g.variable(name).(int)
+	return value
+}
+
+// getString returns the value of a string variable, "" if it is not set.
+func (g *Game) getString(name string) string {
+	value, _ := g.variable(name).(string)
+	return value
+}
+
+// setVariable sets the value of a game variable.
+// Only bool, int and string values can be stored.
+func (g *Game) setVariable(name string, value interface{}) error {
+	if isReserved(name) {
+		return fmt.Errorf("Variable %s is reserved and cannot be set", name)
+	}
+	switch value.(type) {
+	case bool, int, string:
+	default:
+		return fmt.Errorf("Variable %s cannot hold %v", name, value)
+	}
+	if g.Variables == nil {
+		g.Variables = make(map[string]interface{})
+	}
+	g.Variables[name] = value
+	return nil
+}
+
+// holds returns if a condition is true for the current game state.
+func (g *Game) holds(c condition) bool {
+	var result bool
+	switch {
+	case c.Locked != "":
+		result = g.isLocked(toID(c.Locked))
+	case c.Takeable != "":
+		item := g.itemByID(toID(c.Takeable))
+		result = item != nil && item.Takeable
+	case c.State != "":
+		item := g.itemByID(toID(c.State))
+		result = item != nil && item.State == fmt.Sprint(c.Equals)
+	case c.Has != "":
+		result = getItemByID(toID(c.Has), g.Player) != nil
+	case c.Equals != nil:
+		result = fmt.Sprint(g.variable(c.Var)) == fmt.Sprint(c.Equals)
+	case c.Min != nil || c.Max != nil:
+		value := g.getInt(c.Var)
+		result = (c.Min == nil || value >= *c.Min) && (c.Max == nil || value <= *c.Max)
+	default:
+		result = g.getBool(c.Var)
+	}
+	return result != c.Not
+}
+
+// isLocked returns if the item or exit with an id is locked.
+func (g *Game) isLocked(id string) bool {
+	if item := g.itemByID(id); item != nil {
+		return item.Locked
+	}
+	if exit := g.exitByID(id); exit != nil {
+		return exit.Locked
+	}
+	return false
+}
+
+// met returns if every condition in a list is true.
+func (g *Game) met(conditions []condition) bool {
+	for _, c := range conditions {
+		if !g.holds(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply carries out a list of effects on the game variables.
+func (g *Game) apply(effects []effect) {
+	for _, e := range effects {
+		var err error
+		switch {
+		case e.Set != nil:
+			err = g.setVariable(e.Var, e.Set)
+		case e.Add != 0:
+			err = g.setVariable(e.Var, g.getInt(e.Var)+e.Add)
+		default:
+			err = g.setVariable(e.Var, true)
+		}
+		if err != nil {
+			fmt.Fprintln(screen, err)
+		}
+	}
+}
+
+// checkVariables validates the types of the game variables and returns a description
+// of each problem found.
+func (g *Game) checkVariables() []string {
+	var problems []string
+	for name, value := range g.Variables {
+		if isReserved(name) {
+			problems = append(problems, fmt.Sprintf("Variable %s is reserved by the engine", name))
+		}
+		switch value.(type) {
+		case bool, int, string:
+		default:
+			problems = append(problems, fmt.Sprintf("Variable %s must be a bool, int or string", name))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,9 @@
+; Functions shared by the scripts in conf/world.yaml.
+
+; knock is run when the player knocks on a door.
+(define (knock door)
+  (if (= door "upstairs-bathroom-door")
+      (if (locked door)
+          (say "script.knock.locked")
+          (say "script.knock.unlocked"))
+      (say "script.knock.nobody")))